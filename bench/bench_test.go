@@ -44,6 +44,34 @@ func BenchmarkEncodeRequestBody(b *testing.B) {
 	}
 }
 
+// go test -bench=. -benchmem ./bench
+func BenchmarkEncodeRequest(b *testing.B) {
+	req := clientcredentials.Request{
+		GrantType:    "client_credentials",
+		ClientID:     "myclientid",
+		ClientSecret: "myclientsecret",
+		Scope:        "read write",
+	}
+	for b.Loop() {
+		clientcredentials.EncodeRequest(req)
+	}
+}
+
+// go test -bench=. -benchmem ./bench
+func BenchmarkEncodeRequestResource(b *testing.B) {
+	req := clientcredentials.Request{
+		GrantType:    "client_credentials",
+		ClientID:     "myclientid",
+		ClientSecret: "myclientsecret",
+		Scope:        "read write",
+		Audience:     "https://api.example.com",
+		Resource:     []string{"https://a.example.com/", "https://b.example.com/"},
+	}
+	for b.Loop() {
+		clientcredentials.EncodeRequest(req)
+	}
+}
+
 // go test -bench=. -benchmem ./bench
 func BenchmarkDecodeRequestBody(b *testing.B) {
 	reqBody := clientcredentials.EncodeRequestBody("myclientid", "myclientsecret", "read write")
@@ -132,3 +160,16 @@ func BenchmarkDecodeResponseBodyFastJSONSyncPool(b *testing.B) {
 		}
 	}
 }
+
+// go test -bench=. -benchmem ./bench
+func BenchmarkDecodeResponseBodyExtra(b *testing.B) {
+	respBody := clientcredentials.EncodeResponseBody("myaccesstoken", "scope", 3600)
+	data := []byte(respBody)
+
+	for b.Loop() {
+		_, err := clientcredentials.DecodeResponseBodyExtra(data)
+		if err != nil {
+			b.Fatalf("failed to decode response body: %v", err)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package clientcredentials
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Client authentication methods for RequestOptions.AuthMethod.
+// See RFC 6749 section 2.3 and the IANA "OAuth Token Endpoint
+// Authentication Methods" registry.
+const (
+	// AuthMethodClientSecretPost sends client_id and client_secret in the request body.
+	AuthMethodClientSecretPost = "client_secret_post"
+
+	// AuthMethodClientSecretBasic sends client_id and client_secret in the
+	// HTTP Basic Authorization header (RFC 6749 section 2.3.1).
+	AuthMethodClientSecretBasic = "client_secret_basic"
+
+	// AuthMethodNone sends only client_id in the request body.
+	AuthMethodNone = "none"
+
+	// AuthMethodPrivateKeyJWT sends client_id and a client assertion JWT
+	// signed with RequestOptions.PrivateKey (RFC 7523 section 2.2).
+	AuthMethodPrivateKeyJWT = "private_key_jwt"
+
+	// AuthMethodTLSClientAuth sends only client_id; the client is
+	// authenticated by a PKI-issued TLS certificate (RFC 8705 section 2.1).
+	AuthMethodTLSClientAuth = "tls_client_auth"
+
+	// AuthMethodSelfSignedTLSClientAuth sends only client_id; the client is
+	// authenticated by a self-signed TLS certificate (RFC 8705 section 2.2).
+	AuthMethodSelfSignedTLSClientAuth = "self_signed_tls_client_auth"
+)
+
+// ClientAssertionTypeJWTBearer is the client_assertion_type for private_key_jwt.
+const ClientAssertionTypeJWTBearer = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+// applyClientAuth adds client authentication to the request form and headers.
+func applyClientAuth(options RequestOptions, form *Request, header http.Header) error {
+	switch options.AuthMethod {
+	case "", AuthMethodClientSecretPost:
+		form.ClientID = options.ClientID
+		form.ClientSecret = options.ClientSecret
+	case AuthMethodClientSecretBasic:
+		header.Set("Authorization", encodeBasicAuth(options.ClientID, options.ClientSecret))
+	case AuthMethodNone:
+		form.ClientID = options.ClientID
+	case AuthMethodTLSClientAuth, AuthMethodSelfSignedTLSClientAuth:
+		if options.ClientCertificate == nil && options.HTTPClient == nil {
+			return fmt.Errorf("oauth2clientcredentials: %s requires ClientCertificate or HTTPClient", options.AuthMethod)
+		}
+		form.ClientID = options.ClientID
+	case AuthMethodPrivateKeyJWT:
+		assertion, err := newClientAssertion(options)
+		if err != nil {
+			return err
+		}
+		form.ClientID = options.ClientID
+		form.ClientAssertion = assertion
+		form.ClientAssertionType = ClientAssertionTypeJWTBearer
+	default:
+		return fmt.Errorf("oauth2clientcredentials: unsupported auth method: %s", options.AuthMethod)
+	}
+	return nil
+}
+
+// postsClientSecret reports whether client_id and client_secret are sent
+// in the body even if the secret is empty, as client_secret_post always did.
+func postsClientSecret(options RequestOptions) bool {
+	return (options.AuthMethod == "" || options.AuthMethod == AuthMethodClientSecretPost) && options.ClientID != ""
+}
+
+// encodeBasicAuth encodes the Authorization header value for client_secret_basic.
+// RFC 6749 section 2.3.1 requires client_id and client_secret to be
+// form-urlencoded before being base64 encoded.
+func encodeBasicAuth(clientID, clientSecret string) string {
+	creds := url.QueryEscape(clientID) + ":" + url.QueryEscape(clientSecret)
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(creds))
+}
+
+// decodeBasicAuth decodes client credentials from the HTTP Basic
+// Authorization header, reversing the form-urlencoding of encodeBasicAuth.
+func decodeBasicAuth(r *http.Request) (string, string, bool, error) {
+	const prefix = "Basic "
+	auth := r.Header.Get("Authorization")
+	if len(auth) < len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return "", "", false, nil
+	}
+	id, secret, ok := r.BasicAuth()
+	if !ok {
+		return "", "", false, fmt.Errorf("oauth2clientcredentials: malformed basic authorization header")
+	}
+	clientID, errID := url.QueryUnescape(id)
+	if errID != nil {
+		return "", "", false, fmt.Errorf("oauth2clientcredentials: basic authorization client_id: %w", errID)
+	}
+	clientSecret, errSecret := url.QueryUnescape(secret)
+	if errSecret != nil {
+		return "", "", false, fmt.Errorf("oauth2clientcredentials: basic authorization client_secret: %w", errSecret)
+	}
+	return clientID, clientSecret, true, nil
+}
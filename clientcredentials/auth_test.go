@@ -0,0 +1,118 @@
+package clientcredentials
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type authMethodTest struct {
+	name             string
+	authMethod       string
+	expectBasic      bool
+	expectBodyID     bool
+	expectBodySecret bool
+}
+
+var authMethodTests = []authMethodTest{
+	{"default", "", false, true, true},
+	{"client_secret_post", AuthMethodClientSecretPost, false, true, true},
+	{"client_secret_basic", AuthMethodClientSecretBasic, true, false, false},
+	{"none", AuthMethodNone, false, true, false},
+}
+
+func TestAuthMethod(t *testing.T) {
+	const (
+		clientID     = "my client:id"
+		clientSecret = "my+secret%"
+	)
+
+	for _, data := range authMethodTests {
+		t.Run(data.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := r.ParseForm(); err != nil {
+					t.Fatalf("parse form: %v", err)
+				}
+
+				_, _, hasBasic := r.BasicAuth()
+				if hasBasic != data.expectBasic {
+					t.Errorf("expected basic auth=%t, got %t", data.expectBasic, hasBasic)
+				}
+				if got := r.PostForm.Has("client_id"); got != data.expectBodyID {
+					t.Errorf("expected client_id in body=%t, got %t", data.expectBodyID, got)
+				}
+				if got := r.PostForm.Has("client_secret"); got != data.expectBodySecret {
+					t.Errorf("expected client_secret in body=%t, got %t", data.expectBodySecret, got)
+				}
+
+				req, err := DecodeRequestBody(r)
+				if err != nil {
+					t.Fatalf("decode request: %v", err)
+				}
+				if req.ClientID != clientID {
+					t.Errorf("expected client_id '%s', got '%s'", clientID, req.ClientID)
+				}
+				if data.expectBodySecret || data.expectBasic {
+					if req.ClientSecret != clientSecret {
+						t.Errorf("expected client_secret '%s', got '%s'", clientSecret, req.ClientSecret)
+					}
+				}
+
+				w.Write([]byte(EncodeResponseBody("at", "", 60)))
+			}))
+			defer server.Close()
+
+			_, err := SendRequest(context.TODO(), RequestOptions{
+				TokenURL:     server.URL,
+				ClientID:     clientID,
+				ClientSecret: clientSecret,
+				AuthMethod:   data.authMethod,
+			})
+			if err != nil {
+				t.Fatalf("send request: %v", err)
+			}
+		})
+	}
+}
+
+func TestClientSecretPostEmptySecret(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("parse form: %v", err)
+		}
+		// same wire format as EncodeRequestBody
+		if !r.PostForm.Has("client_secret") {
+			t.Errorf("expected empty client_secret in body")
+		}
+		w.Write([]byte(EncodeResponseBody("at", "", 60)))
+	}))
+	defer server.Close()
+
+	_, err := SendRequest(context.TODO(), RequestOptions{
+		TokenURL: server.URL,
+		ClientID: "public-client",
+	})
+	if err != nil {
+		t.Fatalf("send request: %v", err)
+	}
+}
+
+func TestAuthMethodUnsupported(t *testing.T) {
+	_, err := SendRequest(context.TODO(), RequestOptions{
+		TokenURL:   "http://localhost/token",
+		AuthMethod: "bogus",
+	})
+	if err == nil {
+		t.Errorf("expected error for unsupported auth method")
+	}
+}
+
+func TestEncodeBasicAuth(t *testing.T) {
+	got := encodeBasicAuth("a b", "c:d")
+	want := "Basic " + base64.StdEncoding.EncodeToString([]byte("a+b:c%3Ad"))
+	if got != want {
+		t.Errorf("expected '%s', got '%s'", want, got)
+	}
+}
@@ -0,0 +1,203 @@
+package clientcredentials
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultCircuitFailureThreshold is the default number of consecutive
+// failures that opens a CircuitBreaker.
+const DefaultCircuitFailureThreshold = 5
+
+// DefaultCircuitCoolDown is the default time a CircuitBreaker stays open
+// before letting a probe request through.
+const DefaultCircuitCoolDown = 30 * time.Second
+
+// ErrCircuitOpen is returned, without contacting the token endpoint, while
+// the circuit breaker is open. Use errors.Is to detect it.
+var ErrCircuitOpen = errors.New("oauth2clientcredentials: circuit breaker open")
+
+// CircuitState is the state of a CircuitBreaker.
+type CircuitState int
+
+// Circuit breaker states.
+const (
+	CircuitClosed   CircuitState = iota // requests flow normally
+	CircuitOpen                         // requests fail fast with ErrCircuitOpen
+	CircuitHalfOpen                     // a single probe request is allowed
+)
+
+// String implements fmt.Stringer.
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("CircuitState(%d)", int(s))
+}
+
+// CircuitBreakerOptions contains options for creating a CircuitBreaker.
+type CircuitBreakerOptions struct {
+	// FailureThreshold is optional number of consecutive failures that
+	// opens the circuit. If zero, DefaultCircuitFailureThreshold will be used.
+	FailureThreshold int
+
+	// CoolDown is optional time the circuit stays open before a probe
+	// request is let through. If zero, DefaultCircuitCoolDown will be used.
+	CoolDown time.Duration
+
+	// Fetch is optional function used to fetch tokens.
+	// If nil, SendRequest will be used.
+	Fetch FetchFunc
+
+	// IsFailure is optional function that reports whether an error counts
+	// as a token endpoint failure. If nil, DefaultIsCircuitFailure will be used.
+	IsFailure func(err error) bool
+
+	// OnStateChange is optional function called on every state transition,
+	// for example to export metrics. It must not call the CircuitBreaker.
+	OnStateChange func(from, to CircuitState)
+}
+
+// DefaultIsCircuitFailure counts transport errors, like DNS failures,
+// timeouts or refused connections, and 5xx and 429 responses as failures.
+// OAuth2 errors like invalid_client show the endpoint is up and do not
+// count, nor does cancellation.
+func DefaultIsCircuitFailure(err error) bool {
+	var errResp *ErrorResponse
+	if errors.As(err, &errResp) {
+		return errResp.StatusCode >= 500 || errResp.StatusCode == http.StatusTooManyRequests
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	return isTransportError(err)
+}
+
+// CircuitBreaker stops sending token requests to an unhealthy token
+// endpoint. After FailureThreshold consecutive failures the circuit opens
+// and requests fail fast with ErrCircuitOpen. After CoolDown, a single
+// probe request is let through (half-open): success closes the circuit,
+// failure opens it again.
+//
+// SendRequest matches FetchFunc, so a CircuitBreaker can be plugged into
+// TokenSourceOptions.Fetch or RequestGroup.Fetch.
+// CircuitBreaker is safe for concurrent use by multiple goroutines.
+type CircuitBreaker struct {
+	options CircuitBreakerOptions
+
+	mutex    sync.Mutex
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	probing  bool
+
+	now func() time.Time
+}
+
+// NewCircuitBreaker creates a CircuitBreaker in the closed state.
+func NewCircuitBreaker(options CircuitBreakerOptions) *CircuitBreaker {
+	if options.FailureThreshold == 0 {
+		options.FailureThreshold = DefaultCircuitFailureThreshold
+	}
+	if options.CoolDown == 0 {
+		options.CoolDown = DefaultCircuitCoolDown
+	}
+	if options.Fetch == nil {
+		options.Fetch = SendRequest
+	}
+	if options.IsFailure == nil {
+		options.IsFailure = DefaultIsCircuitFailure
+	}
+	return &CircuitBreaker{options: options, now: time.Now}
+}
+
+// State returns the current state, for example for health checks.
+// An open circuit whose cool-down has elapsed is reported as half-open.
+func (cb *CircuitBreaker) State() CircuitState {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	if cb.state == CircuitOpen && cb.now().Sub(cb.openedAt) >= cb.options.CoolDown {
+		return CircuitHalfOpen
+	}
+	return cb.state
+}
+
+// SendRequest fetches a token unless the circuit is open.
+func (cb *CircuitBreaker) SendRequest(ctx context.Context, options RequestOptions) (Response, error) {
+	if err := cb.allow(); err != nil {
+		return Response{}, err
+	}
+
+	resp, err := cb.options.Fetch(ctx, options)
+
+	cb.record(ctx, err)
+
+	return resp, err
+}
+
+// allow admits a request, or reports the circuit open.
+func (cb *CircuitBreaker) allow() error {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	if cb.state == CircuitOpen {
+		remaining := cb.options.CoolDown - cb.now().Sub(cb.openedAt)
+		if remaining > 0 {
+			return fmt.Errorf("%w: retry in %v", ErrCircuitOpen, remaining)
+		}
+		cb.setStateLocked(CircuitHalfOpen)
+	}
+
+	if cb.state == CircuitHalfOpen {
+		if cb.probing {
+			return fmt.Errorf("%w: probe in flight", ErrCircuitOpen)
+		}
+		cb.probing = true
+	}
+
+	return nil
+}
+
+// record updates the state with the outcome of an admitted request.
+func (cb *CircuitBreaker) record(ctx context.Context, err error) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	cb.probing = false
+
+	if err != nil && ctx.Err() != nil {
+		return // caller gave up, outcome unknown
+	}
+
+	switch {
+	case err == nil || !cb.options.IsFailure(err):
+		cb.failures = 0
+		cb.setStateLocked(CircuitClosed)
+	default:
+		cb.failures++
+		if cb.state == CircuitHalfOpen || cb.failures >= cb.options.FailureThreshold {
+			cb.openedAt = cb.now()
+			cb.setStateLocked(CircuitOpen)
+		}
+	}
+}
+
+func (cb *CircuitBreaker) setStateLocked(state CircuitState) {
+	if cb.state == state {
+		return
+	}
+	from := cb.state
+	cb.state = state
+	if cb.options.OnStateChange != nil {
+		cb.options.OnStateChange(from, state)
+	}
+}
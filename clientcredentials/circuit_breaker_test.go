@@ -0,0 +1,225 @@
+package clientcredentials
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var fetchErr error
+	var calls int
+
+	var transitions []string
+
+	cb := NewCircuitBreaker(CircuitBreakerOptions{
+		FailureThreshold: 3,
+		CoolDown:         10 * time.Second,
+		Fetch: func(ctx context.Context, options RequestOptions) (Response, error) {
+			calls++
+			return Response{AccessToken: "at"}, fetchErr
+		},
+		OnStateChange: func(from, to CircuitState) {
+			transitions = append(transitions, from.String()+"->"+to.String())
+		},
+	})
+
+	now := time.Now()
+	cb.now = func() time.Time { return now }
+
+	send := func() error {
+		_, err := cb.SendRequest(context.TODO(), RequestOptions{})
+		return err
+	}
+
+	// failures below the threshold keep the circuit closed
+	fetchErr = &ErrorResponse{StatusCode: http.StatusServiceUnavailable}
+	send()
+	send()
+	if cb.State() != CircuitClosed {
+		t.Fatalf("expected closed, got %s", cb.State())
+	}
+
+	// an OAuth2 error resets the failure count
+	fetchErr = &ErrorResponse{StatusCode: http.StatusUnauthorized, Code: ErrorCodeInvalidClient}
+	send()
+	fetchErr = io.ErrUnexpectedEOF
+	send()
+	send()
+	if cb.State() != CircuitClosed {
+		t.Fatalf("expected closed after reset, got %s", cb.State())
+	}
+
+	// threshold reached
+	send()
+	if cb.State() != CircuitOpen {
+		t.Fatalf("expected open, got %s", cb.State())
+	}
+
+	// open circuit fails fast
+	calls = 0
+	if err := send(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("expected ErrCircuitOpen, got %v", err)
+	}
+	if calls != 0 {
+		t.Errorf("open circuit should not fetch, got %d calls", calls)
+	}
+
+	// failed probe opens again
+	now = now.Add(10 * time.Second)
+	if cb.State() != CircuitHalfOpen {
+		t.Fatalf("expected half-open after cool-down, got %s", cb.State())
+	}
+	fetchErr = &ErrorResponse{StatusCode: http.StatusTooManyRequests}
+	send()
+	if cb.State() != CircuitOpen {
+		t.Fatalf("expected open after failed probe, got %s", cb.State())
+	}
+
+	// successful probe closes
+	now = now.Add(10 * time.Second)
+	fetchErr = nil
+	if err := send(); err != nil {
+		t.Errorf("unexpected probe error: %v", err)
+	}
+	if cb.State() != CircuitClosed {
+		t.Fatalf("expected closed after successful probe, got %s", cb.State())
+	}
+
+	want := []string{"closed->open", "open->half-open", "half-open->open", "open->half-open", "half-open->closed"}
+	if len(transitions) != len(want) {
+		t.Fatalf("expected transitions %v, got %v", want, transitions)
+	}
+	for i := range want {
+		if transitions[i] != want[i] {
+			t.Errorf("transition %d: expected %s, got %s", i, want[i], transitions[i])
+		}
+	}
+}
+
+func TestCircuitBreakerSingleProbe(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	failing := true
+
+	cb := NewCircuitBreaker(CircuitBreakerOptions{
+		FailureThreshold: 1,
+		CoolDown:         time.Second,
+		Fetch: func(ctx context.Context, options RequestOptions) (Response, error) {
+			if failing {
+				return Response{}, io.EOF
+			}
+			close(started)
+			<-release
+			return Response{AccessToken: "at"}, nil
+		},
+	})
+
+	now := time.Now()
+	cb.now = func() time.Time { return now }
+
+	cb.SendRequest(context.TODO(), RequestOptions{})
+	if cb.State() != CircuitOpen {
+		t.Fatalf("expected open, got %s", cb.State())
+	}
+
+	now = now.Add(time.Second)
+	failing = false
+
+	done := make(chan error)
+	go func() {
+		_, err := cb.SendRequest(context.TODO(), RequestOptions{})
+		done <- err
+	}()
+	<-started
+
+	if _, err := cb.SendRequest(context.TODO(), RequestOptions{}); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("expected ErrCircuitOpen while probing, got %v", err)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("unexpected probe error: %v", err)
+	}
+	if cb.State() != CircuitClosed {
+		t.Errorf("expected closed, got %s", cb.State())
+	}
+}
+
+func TestCircuitBreakerTransportErrors(t *testing.T) {
+	release := make(chan struct{})
+	hanging := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		<-release
+	}))
+	defer hanging.Close()
+	defer close(release)
+
+	for name, tokenURL := range map[string]string{
+		"unresolvable host": "http://idp.invalid/token",
+		"client timeout":    hanging.URL,
+	} {
+		cb := NewCircuitBreaker(CircuitBreakerOptions{FailureThreshold: 2})
+		options := RequestOptions{
+			TokenURL:   tokenURL,
+			HTTPClient: &http.Client{Timeout: 100 * time.Millisecond},
+		}
+		for range 2 {
+			cb.SendRequest(context.TODO(), options)
+		}
+		if cb.State() != CircuitOpen {
+			t.Errorf("%s: expected open, got %s", name, cb.State())
+		}
+	}
+}
+
+func TestCircuitBreakerCallerCanceled(t *testing.T) {
+	cb := NewCircuitBreaker(CircuitBreakerOptions{
+		FailureThreshold: 1,
+		Fetch: func(ctx context.Context, _ RequestOptions) (Response, error) {
+			return Response{}, &url.Error{Op: "Post", URL: "x", Err: ctx.Err()}
+		},
+	})
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+
+	cb.SendRequest(ctx, RequestOptions{})
+	if cb.State() != CircuitClosed {
+		t.Errorf("expected caller deadline not to count, got %s", cb.State())
+	}
+}
+
+func TestDefaultIsCircuitFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"500", &ErrorResponse{StatusCode: 500}, true},
+		{"503", &ErrorResponse{StatusCode: 503}, true},
+		{"429", &ErrorResponse{StatusCode: 429}, true},
+		{"400 invalid_grant", &ErrorResponse{StatusCode: 400, Code: ErrorCodeInvalidGrant}, false},
+		{"401 invalid_client", &ErrorResponse{StatusCode: 401, Code: ErrorCodeInvalidClient}, false},
+		{"eof", io.EOF, true},
+		{"unknown authority", &url.Error{Op: "Post", URL: "x", Err: x509.UnknownAuthorityError{}}, true},
+		{"no such host", &url.Error{Op: "Post", URL: "x", Err: &net.DNSError{Err: "no such host", IsNotFound: true}}, true},
+		{"client timeout", &url.Error{Op: "Post", URL: "x", Err: context.DeadlineExceeded}, true},
+		{"canceled request", &url.Error{Op: "Post", URL: "x", Err: context.Canceled}, false},
+		{"canceled", context.Canceled, false},
+		{"circuit open", ErrCircuitOpen, false},
+	}
+	for _, tt := range tests {
+		if got := DefaultIsCircuitFailure(tt.err); got != tt.want {
+			t.Errorf("%s: expected %t, got %t", tt.name, tt.want, got)
+		}
+	}
+}
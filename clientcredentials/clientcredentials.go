@@ -4,10 +4,14 @@ package clientcredentials
 
 import (
 	"context"
+	"crypto"
+	"crypto/tls"
+	"encoding/json"
 	"fmt"
-	"io"
+	"maps"
 	"net/http"
 	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -47,7 +51,114 @@ func EncodeRequestBody(clientID, clientSecret, scope string) string {
 	return clientIDEncoded + "=" + url.QueryEscape(clientID) + clientSecretEncoded + "=" + url.QueryEscape(clientSecret) + grantTypeEncoded
 }
 
+// EncodeRequest encodes the request body for a token request.
+// Empty fields are omitted, so unlike EncodeRequestBody an empty
+// client_secret is not sent. Otherwise, for the same fields, it produces
+// the same encoding as EncodeRequestBody. With client_secret_post and a
+// ClientID, SendRequest still sends client_secret even if empty.
+func EncodeRequest(req Request) string {
+	return encodeRequest(req, false)
+}
+
+// encodeRequest encodes req, sending client_id and client_secret even if
+// empty when postCredentials is set.
+func encodeRequest(req Request, postCredentials bool) string {
+	var b strings.Builder
+	size := 64 + len(req.ClientID) + len(req.ClientSecret) + len(req.Scope) +
+		len(req.ClientAssertion) + len(req.Audience) + len(req.SubjectToken) + len(req.ActorToken) +
+		len(req.Assertion)
+	for _, r := range req.Resource {
+		size += 10 + len(r)
+	}
+	b.Grow(size)
+
+	if postCredentials {
+		writeParam(&b, "client_id", req.ClientID)
+		writeParam(&b, "client_secret", req.ClientSecret)
+	} else {
+		appendParam(&b, "client_id", req.ClientID)
+		appendParam(&b, "client_secret", req.ClientSecret)
+	}
+	appendParam(&b, "grant_type", req.GrantType)
+	appendParam(&b, "scope", req.Scope)
+	appendParam(&b, "client_assertion", req.ClientAssertion)
+	appendParam(&b, "client_assertion_type", req.ClientAssertionType)
+	appendParam(&b, "audience", req.Audience)
+	for _, r := range req.Resource {
+		appendParam(&b, "resource", r)
+	}
+	appendParam(&b, "subject_token", req.SubjectToken)
+	appendParam(&b, "subject_token_type", req.SubjectTokenType)
+	appendParam(&b, "actor_token", req.ActorToken)
+	appendParam(&b, "actor_token_type", req.ActorTokenType)
+	appendParam(&b, "requested_token_type", req.RequestedTokenType)
+	appendParam(&b, "assertion", req.Assertion)
+	if len(req.Extra) > 0 {
+		appendExtra(&b, req.Extra)
+	}
+
+	return b.String()
+}
+
+// standardParams are the parameters encoded from dedicated Request fields.
+var standardParams = map[string]bool{
+	"grant_type":            true,
+	"client_id":             true,
+	"client_secret":         true,
+	"scope":                 true,
+	"client_assertion":      true,
+	"client_assertion_type": true,
+	"audience":              true,
+	"resource":              true,
+	"subject_token":         true,
+	"subject_token_type":    true,
+	"actor_token":           true,
+	"actor_token_type":      true,
+	"requested_token_type":  true,
+	"assertion":             true,
+}
+
+// appendExtra appends extra parameters to b sorted by key,
+// skipping standard parameters.
+func appendExtra(b *strings.Builder, extra url.Values) {
+	for _, k := range slices.Sorted(maps.Keys(extra)) {
+		if standardParams[k] {
+			continue
+		}
+		keyEscaped := url.QueryEscape(k)
+		for _, v := range extra[k] {
+			if b.Len() > 0 {
+				b.WriteByte('&')
+			}
+			b.WriteString(keyEscaped)
+			b.WriteByte('=')
+			b.WriteString(url.QueryEscape(v))
+		}
+	}
+}
+
+// appendParam appends key=value to b, unless value is empty.
+// key must not require escaping.
+func appendParam(b *strings.Builder, key, value string) {
+	if value == "" {
+		return
+	}
+	writeParam(b, key, value)
+}
+
+// writeParam appends key=value to b, even if value is empty.
+func writeParam(b *strings.Builder, key, value string) {
+	if b.Len() > 0 {
+		b.WriteByte('&')
+	}
+	b.WriteString(key)
+	b.WriteByte('=')
+	b.WriteString(url.QueryEscape(value))
+}
+
 // DecodeRequestBody decodes the request body for client credentials grant type.
+// If the body carries no client_id, client credentials are taken from
+// the HTTP Basic Authorization header (client_secret_basic), if present.
 func DecodeRequestBody(r *http.Request) (Request, error) {
 
 	var req Request
@@ -60,6 +171,35 @@ func DecodeRequestBody(r *http.Request) (Request, error) {
 	req.ClientID = getParam(r, "client_id")
 	req.ClientSecret = getParam(r, "client_secret")
 	req.Scope = getParam(r, "scope")
+	req.ClientAssertion = getParam(r, "client_assertion")
+	req.ClientAssertionType = getParam(r, "client_assertion_type")
+	req.Audience = getParam(r, "audience")
+	req.Resource = r.Form["resource"]
+	req.SubjectToken = getParam(r, "subject_token")
+	req.SubjectTokenType = getParam(r, "subject_token_type")
+	req.ActorToken = getParam(r, "actor_token")
+	req.ActorTokenType = getParam(r, "actor_token_type")
+	req.RequestedTokenType = getParam(r, "requested_token_type")
+	req.Assertion = getParam(r, "assertion")
+
+	for k, v := range r.Form {
+		if standardParams[k] {
+			continue
+		}
+		if req.Extra == nil {
+			req.Extra = url.Values{}
+		}
+		req.Extra[k] = v
+	}
+
+	if req.ClientID == "" {
+		if id, secret, found, err := decodeBasicAuth(r); err != nil {
+			return req, err
+		} else if found {
+			req.ClientID = id
+			req.ClientSecret = secret
+		}
+	}
 
 	return req, nil
 }
@@ -70,6 +210,34 @@ type Request struct {
 	ClientID     string
 	ClientSecret string
 	Scope        string
+
+	// ClientAssertion and ClientAssertionType carry the client
+	// authentication assertion for private_key_jwt (RFC 7523).
+	ClientAssertion     string
+	ClientAssertionType string
+
+	// SubjectToken, SubjectTokenType, ActorToken, ActorTokenType and
+	// RequestedTokenType are the token exchange parameters (RFC 8693).
+	SubjectToken       string
+	SubjectTokenType   string
+	ActorToken         string
+	ActorTokenType     string
+	RequestedTokenType string
+
+	// Assertion is the authorization grant for the JWT bearer grant
+	// type (RFC 7523 section 2.1).
+	Assertion string
+
+	// Audience is the audience parameter used by some providers (e.g. Auth0).
+	Audience string
+
+	// Resource holds resource indicators (RFC 8707), sent as repeated
+	// resource parameters.
+	Resource []string
+
+	// Extra holds non-standard parameters. When encoding, keys that
+	// collide with standard parameters are ignored.
+	Extra url.Values
 }
 
 func getParam(r *http.Request, key string) string {
@@ -117,15 +285,7 @@ func DecodeResponseBodyFastJSON(data []byte) (Response, error) {
 		return resp, err
 	}
 
-	// Extract fields
-	resp.AccessToken = string(v.GetStringBytes("access_token"))
-	resp.TokenType = string(v.GetStringBytes("token_type"))
-
-	// GetInt returns 0 if the key is missing or not an integer
-	resp.ExpiresIn = v.GetInt("expires_in")
-	resp.Scope = string(v.GetStringBytes("scope"))
-
-	return resp, nil
+	return decodeFastJSONValue(v)
 }
 
 var parserPool = sync.Pool{
@@ -145,22 +305,92 @@ func DecodeResponseBodyFastJSONSyncPool(data []byte) (Response, error) {
 		return resp, err
 	}
 
+	return decodeFastJSONValue(v)
+}
+
+// decodeFastJSONValue extracts the well-known fields from a parsed response.
+func decodeFastJSONValue(v *fastjson.Value) (Response, error) {
+	var resp Response
+
+	expiresIn, errExpire := expiresInFromFastJSON(v.Get("expires_in"))
+	if errExpire != nil {
+		return resp, errExpire
+	}
+
 	// Direct assignment avoids reflection
 	resp.AccessToken = string(v.GetStringBytes("access_token"))
 	resp.TokenType = string(v.GetStringBytes("token_type"))
-	resp.ExpiresIn = v.GetInt("expires_in")
+	resp.ExpiresIn = expiresIn
 	resp.Scope = string(v.GetStringBytes("scope"))
+	resp.IssuedTokenType = string(v.GetStringBytes("issued_token_type"))
+	resp.RefreshToken = string(v.GetStringBytes("refresh_token"))
+	resp.IDToken = string(v.GetStringBytes("id_token"))
+
+	return resp, nil
+}
+
+// responseFields are the fields mapped to dedicated Response fields.
+var responseFields = map[string]bool{
+	"access_token":      true,
+	"token_type":        true,
+	"expires_in":        true,
+	"scope":             true,
+	"issued_token_type": true,
+	"refresh_token":     true,
+	"id_token":          true,
+}
+
+// DecodeResponseBodyExtra decodes the response body like DecodeResponseBody,
+// additionally preserving unknown fields in Response.Extra.
+// It is slower than DecodeResponseBody, which skips unknown fields.
+func DecodeResponseBodyExtra(data []byte) (Response, error) {
+	p := parserPool.Get().(*fastjson.Parser)
+	defer parserPool.Put(p)
+
+	v, err := p.ParseBytes(data)
+	if err != nil {
+		return Response{}, err
+	}
+
+	resp, errDecode := decodeFastJSONValue(v)
+	if errDecode != nil {
+		return resp, errDecode
+	}
+
+	obj, errObj := v.Object()
+	if errObj != nil {
+		return resp, errObj
+	}
+
+	obj.Visit(func(key []byte, value *fastjson.Value) {
+		if responseFields[string(key)] {
+			return
+		}
+		if resp.Extra == nil {
+			resp.Extra = map[string]json.RawMessage{}
+		}
+		// MarshalTo copies, so the value outlives the pooled parser
+		resp.Extra[string(key)] = value.MarshalTo(nil)
+	})
 
 	return resp, nil
 }
 
 // DecodeResponseBodySonnet decodes the response body for client credentials grant type using sonnet.
 func DecodeResponseBodySonnet(data []byte) (Response, error) {
-	var resp Response
-	err := sonnet.Unmarshal(data, &resp)
+	var lenient sonnetResponse
+	err := sonnet.Unmarshal(data, &lenient)
+	resp := lenient.Response
+	resp.ExpiresIn = int(lenient.ExpiresIn)
 	return resp, err
 }
 
+// sonnetResponse shadows Response.ExpiresIn with a lenient decoder.
+type sonnetResponse struct {
+	Response
+	ExpiresIn expiresInValue `json:"expires_in"`
+}
+
 // DecodeResponseBodyCustomParser decodes the response body for client credentials grant type using a custom parser.
 func DecodeResponseBodyCustomParser(data []byte) (Response, error) {
 	return parseToken(data, func(_ string, _ ...any) {})
@@ -172,6 +402,17 @@ type Response struct {
 	TokenType   string `json:"token_type"`
 	ExpiresIn   int    `json:"expires_in,omitempty"`
 	Scope       string `json:"scope,omitempty"`
+
+	// IssuedTokenType is the type of the issued token in token exchange
+	// responses (RFC 8693 section 2.2.1).
+	IssuedTokenType string `json:"issued_token_type,omitempty"`
+
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+
+	// Extra holds the raw JSON value of every field not mapped above, like
+	// ext_expires_in. It is filled only by DecodeResponseBodyExtra.
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // HTTPDoer is an interface for plugging in custom HTTP clients.
@@ -190,9 +431,73 @@ type RequestOptions struct {
 	ClientSecret string
 	Scope        string
 
+	// Audience is optional audience parameter.
+	Audience string
+
+	// Resource is optional list of resource indicators (RFC 8707).
+	Resource []string
+
+	// ExtraParams is optional set of non-standard parameters added to the
+	// request body. Keys that collide with standard parameters are ignored.
+	ExtraParams url.Values
+
+	// ExtraHeaders is optional set of headers added to the request.
+	// Content-Type and client authentication headers take precedence.
+	ExtraHeaders http.Header
+
+	// AuthMethod is optional client authentication method.
+	// If empty, AuthMethodClientSecretPost will be used.
+	AuthMethod string
+
+	// PrivateKey signs the client assertion for AuthMethodPrivateKeyJWT.
+	// Supported keys are *rsa.PrivateKey, *ecdsa.PrivateKey and ed25519.PrivateKey.
+	PrivateKey crypto.Signer
+
+	// KeyID is optional key ID sent as the kid header of the client assertion.
+	KeyID string
+
+	// ClientCertificate is optional certificate for mutual TLS client
+	// authentication (AuthMethodTLSClientAuth, AuthMethodSelfSignedTLSClientAuth).
+	// It is used only when HTTPClient is nil; the server is then verified
+	// against the host root CA set. For custom roots, set HTTPClient to
+	// NewMTLSHTTPClient instead. Clients are shared per certificate
+	// fingerprint in a small bounded cache, so reloading the certificate
+	// on rotation does not leak connections.
+	ClientCertificate *tls.Certificate
+
 	// IsStatusCodeOK is optional function to check if the status code is OK.
 	// If nil, DefaultIsStatusCodeOK will be used.
 	IsStatusCodeOK func(statusCode int) error
+
+	// DecodeResponse is optional function to decode the response body.
+	// It must not retain the data slice after returning, see ResponseDecoder.
+	// Use Strict to validate responses.
+	// If nil, DecodeResponseBody will be used.
+	DecodeResponse ResponseDecoder
+
+	// SecretProvider is optional source of the client secret, consulted
+	// on every request. If set, ClientSecret is ignored. When the token
+	// endpoint replies invalid_client, the provider is invalidated and the
+	// request is repeated once.
+	SecretProvider SecretProvider
+
+	// Endpoints is optional pool of token endpoints to fail over across.
+	// If set, TokenURL is ignored.
+	Endpoints *EndpointPool
+
+	// DPoP is optional DPoP proof generator. If set, token requests carry
+	// a DPoP proof and the server may issue DPoP-bound tokens (RFC 9449).
+	DPoP *DPoP
+
+	// MaxResponseSize is optional maximum size of the response body.
+	// Larger successful responses fail with ErrResponseTooLarge; larger
+	// error responses are truncated and still reported as *ErrorResponse.
+	// If zero, DefaultMaxResponseSize will be used.
+	MaxResponseSize int64
+
+	// Retry is optional policy for retrying transient failures.
+	// If nil, failed requests are not retried.
+	Retry *RetryPolicy
 }
 
 // DefaultIsStatusCodeOK is the default implementation for checking if a status code is OK.
@@ -205,42 +510,176 @@ func DefaultIsStatusCodeOK(statusCode int) error {
 
 // SendRequest sends a client credentials token request and returns the response.
 func SendRequest(ctx context.Context, options RequestOptions) (Response, error) {
+	form := Request{
+		GrantType: "client_credentials",
+		Scope:     options.Scope,
+		Audience:  options.Audience,
+		Resource:  options.Resource,
+		Extra:     options.ExtraParams,
+	}
+	return sendTokenRequest(ctx, options, tokenRequest{form: form, caller: "SendRequest"})
+}
+
+// assertionMinter mints a JWT bearer assertion for the token endpoint at
+// tokenURL.
+type assertionMinter func(tokenURL string) (string, error)
+
+// tokenRequest is a token request form with how to complete it on each
+// attempt.
+type tokenRequest struct {
+	form Request
+
+	// caller is the public entry point, like SendRequest, used as prefix
+	// of error responses.
+	caller string
+
+	// mint is optional. If set, it mints form.Assertion for each attempt,
+	// so every attempt gets a fresh jti.
+	mint assertionMinter
+}
+
+// sendTokenRequest sends a token request, retrying as defined by options.Retry.
+func sendTokenRequest(ctx context.Context, options RequestOptions, req tokenRequest) (Response, error) {
+	return sendWithPolicy(ctx, options, func() (Response, error) {
+		return sendTokenRequestEndpoints(ctx, options, req)
+	})
+}
+
+// sendWithPolicy runs send under the retry policy, repeating it once with
+// a fresh secret when the server replies invalid_client.
+func sendWithPolicy[T any](ctx context.Context, options RequestOptions, send func() (T, error)) (T, error) {
+	return retryWithPolicy(ctx, options.Retry, func() (T, error) {
+		resp, err := send()
+		if options.SecretProvider != nil && usesClientSecret(options.AuthMethod) && isInvalidClient(err) {
+			// the secret may have been rotated, read it again
+			options.SecretProvider.Invalidate()
+			return send()
+		}
+		return resp, err
+	})
+}
+
+// sendTokenRequestEndpoints sends the request to the endpoint pool, if any,
+// or to options.TokenURL.
+func sendTokenRequestEndpoints(ctx context.Context, options RequestOptions, req tokenRequest) (Response, error) {
+	if options.Endpoints != nil {
+		return options.Endpoints.send(ctx, options, req)
+	}
+	return sendTokenRequestAttempt(ctx, options, req)
+}
+
+// sendTokenRequestAttempt sends the request to options.TokenURL, repeating
+// it once if the server asks for a DPoP nonce.
+func sendTokenRequestAttempt(ctx context.Context, options RequestOptions, req tokenRequest) (Response, error) {
+	resp, err := sendTokenRequestOnce(ctx, options, req)
+	if options.DPoP != nil && isDPoPNonceError(err) {
+		// the nonce was recorded, repeat with a new proof
+		return sendTokenRequestOnce(ctx, options, req)
+	}
+	return resp, err
+}
+
+// sendTokenRequestOnce authenticates the client as selected by options,
+// posts the request to the token endpoint and decodes the response.
+func sendTokenRequestOnce(ctx context.Context, options RequestOptions, req tokenRequest) (Response, error) {
+	decode := options.DecodeResponse
+	if decode == nil {
+		decode = DecodeResponseBody
+	}
+	return postForm(ctx, options, req.form, req.mint, req.caller, decode)
+}
+
+// postForm authenticates the client as selected by options, posts form to
+// options.TokenURL and decodes the response with decode. If mint is not
+// nil, it mints form.Assertion for options.TokenURL. Error responses are
+// reported as *ErrorResponse, prefixed with caller.
+func postForm[T any](ctx context.Context, options RequestOptions, form Request, mint assertionMinter,
+	caller string, decode func(data []byte) (T, error)) (T, error) {
+
+	var result T
 
-	var tokenResp Response
+	header := options.ExtraHeaders.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+
+	if options.SecretProvider != nil && usesClientSecret(options.AuthMethod) {
+		secret, errSecret := options.SecretProvider.ClientSecret(ctx)
+		if errSecret != nil {
+			return result, errSecret
+		}
+		options.ClientSecret = secret
+	}
+
+	if err := applyClientAuth(options, &form, header); err != nil {
+		return result, err
+	}
+
+	if mint != nil {
+		assertion, errMint := mint(options.TokenURL)
+		if errMint != nil {
+			return result, errMint
+		}
+		form.Assertion = assertion
+	}
 
 	if options.HTTPClient == nil {
-		options.HTTPClient = http.DefaultClient
+		if options.ClientCertificate != nil {
+			options.HTTPClient = mtlsClient(options.ClientCertificate)
+		} else {
+			options.HTTPClient = http.DefaultClient
+		}
 	}
 
 	if options.IsStatusCodeOK == nil {
 		options.IsStatusCodeOK = DefaultIsStatusCodeOK
 	}
 
-	reqBody := EncodeRequestBody(options.ClientID, options.ClientSecret, options.Scope)
+	if options.MaxResponseSize == 0 {
+		options.MaxResponseSize = DefaultMaxResponseSize
+	}
+
+	reqBody := encodeRequest(form, postsClientSecret(options))
 
 	req, errReq := http.NewRequestWithContext(ctx, "POST", options.TokenURL,
 		strings.NewReader(reqBody))
 	if errReq != nil {
-		return tokenResp, errReq
+		return result, errReq
 	}
 
+	req.Header = header
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
+	if options.DPoP != nil {
+		proof, errProof := options.DPoP.Proof(req.Method, options.TokenURL, "")
+		if errProof != nil {
+			return result, errProof
+		}
+		req.Header.Set("DPoP", proof)
+	}
+
 	resp, errDo := options.HTTPClient.Do(req)
 	if errDo != nil {
-		return tokenResp, errDo
+		return result, errDo
+	}
+
+	if options.DPoP != nil {
+		options.DPoP.updateNonce(req.URL, resp.Header)
 	}
 
 	defer resp.Body.Close()
 
 	if err := options.IsStatusCodeOK(resp.StatusCode); err != nil {
-		return tokenResp, fmt.Errorf("oauth2clientcredentials.SendRequest error: %w", err)
+		body := readErrorBody(resp.Body, options.MaxResponseSize)
+		return result, fmt.Errorf("oauth2clientcredentials.%s error: %w", caller,
+			newErrorResponse(resp, body, err))
 	}
 
-	body, errRead := io.ReadAll(resp.Body)
+	buf, errRead := readBody(resp.Body, resp.ContentLength, options.MaxResponseSize)
 	if errRead != nil {
-		return tokenResp, errRead
+		return result, errRead
 	}
+	defer putBuffer(buf)
 
-	return DecodeResponseBody(body)
+	return decode(buf.Bytes())
 }
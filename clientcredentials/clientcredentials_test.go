@@ -6,6 +6,9 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"slices"
 	"strings"
 	"testing"
 )
@@ -131,6 +134,16 @@ func TestEncodeRequestBody(t *testing.T) {
 				t.Fatalf("expected '%s', got '%s'", data.expected, result)
 			}
 
+			resultReq := EncodeRequest(Request{
+				GrantType:    "client_credentials",
+				ClientID:     data.clientID,
+				ClientSecret: data.clientSecret,
+				Scope:        data.scope,
+			})
+			if resultReq != data.expected {
+				t.Fatalf("EncodeRequest: expected '%s', got '%s'", data.expected, resultReq)
+			}
+
 			req, errReq := http.NewRequest("POST", "http://example.com/token", io.NopCloser(strings.NewReader(result)))
 			if errReq != nil {
 				t.Fatalf("failed to create request: %v", errReq)
@@ -182,9 +195,9 @@ func TestDecodeResponseBody(t *testing.T) {
 			wantErr: true,
 		},
 		{
-			name:  "unexpected type for expires_in", // fastjson may return 0
-			input: `{"access_token":"at","expires_in":"not-a-number"}`,
-			want:  Response{AccessToken: "at", ExpiresIn: 0},
+			name:    "unexpected type for expires_in",
+			input:   `{"access_token":"at","expires_in":"not-a-number"}`,
+			wantErr: true,
 		},
 	}
 
@@ -194,9 +207,203 @@ func TestDecodeResponseBody(t *testing.T) {
 			if (err != nil) != tt.wantErr {
 				t.Fatalf("DecodeResponseBody() error = %v, wantErr %v", err, tt.wantErr)
 			}
-			if !tt.wantErr && got != tt.want {
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("DecodeResponseBody() = %+v, want %+v", got, tt.want)
 			}
 		})
 	}
 }
+
+// go test -count 1 -run ^TestEncodeRequestResource$ ./...
+func TestEncodeRequestResource(t *testing.T) {
+	data := Request{
+		GrantType:    "client_credentials",
+		ClientID:     "myclientid",
+		ClientSecret: "myclientsecret",
+		Audience:     "https://api.example.com",
+		Resource:     []string{"https://a.example.com/", "https://b.example.com/x?y=1"},
+	}
+
+	const expected = "client_id=myclientid&client_secret=myclientsecret&grant_type=client_credentials" +
+		"&audience=https%3A%2F%2Fapi.example.com" +
+		"&resource=https%3A%2F%2Fa.example.com%2F&resource=https%3A%2F%2Fb.example.com%2Fx%3Fy%3D1"
+
+	result := EncodeRequest(data)
+	if result != expected {
+		t.Fatalf("expected '%s', got '%s'", expected, result)
+	}
+
+	req, errReq := http.NewRequest("POST", "http://example.com/token", strings.NewReader(result))
+	if errReq != nil {
+		t.Fatalf("failed to create request: %v", errReq)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	decodedReq, errDecode := DecodeRequestBody(req)
+	if errDecode != nil {
+		t.Fatalf("failed to decode request body: %v", errDecode)
+	}
+
+	if decodedReq.Audience != data.Audience {
+		t.Errorf("expected audience %s, got %s", data.Audience, decodedReq.Audience)
+	}
+	if !slices.Equal(decodedReq.Resource, data.Resource) {
+		t.Errorf("expected resource %v, got %v", data.Resource, decodedReq.Resource)
+	}
+}
+
+// go test -count 1 -run ^TestEncodeRequestExtra$ ./...
+func TestEncodeRequestExtra(t *testing.T) {
+	data := Request{
+		GrantType: "client_credentials",
+		ClientID:  "myclientid",
+		Extra: url.Values{
+			"tenant":     {"t 1"},
+			"z":          {"1", "2"},
+			"grant_type": {"password"}, // ignored
+		},
+	}
+
+	const expected = "client_id=myclientid&grant_type=client_credentials&tenant=t+1&z=1&z=2"
+
+	result := EncodeRequest(data)
+	if result != expected {
+		t.Fatalf("expected '%s', got '%s'", expected, result)
+	}
+
+	req, errReq := http.NewRequest("POST", "http://example.com/token", strings.NewReader(result))
+	if errReq != nil {
+		t.Fatalf("failed to create request: %v", errReq)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	decodedReq, errDecode := DecodeRequestBody(req)
+	if errDecode != nil {
+		t.Fatalf("failed to decode request body: %v", errDecode)
+	}
+
+	want := url.Values{"tenant": {"t 1"}, "z": {"1", "2"}}
+	if !reflect.DeepEqual(decodedReq.Extra, want) {
+		t.Errorf("expected extra %v, got %v", want, decodedReq.Extra)
+	}
+	if decodedReq.GrantType != "client_credentials" {
+		t.Errorf("expected grant_type client_credentials, got %s", decodedReq.GrantType)
+	}
+}
+
+func TestEncodeRequestRoundTrip(t *testing.T) {
+	decode := func(body string) Request {
+		t.Helper()
+		req, errReq := http.NewRequest("POST", "http://example.com/token", strings.NewReader(body))
+		if errReq != nil {
+			t.Fatalf("failed to create request: %v", errReq)
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		decoded, errDecode := DecodeRequestBody(req)
+		if errDecode != nil {
+			t.Fatalf("failed to decode request body: %v", errDecode)
+		}
+		return decoded
+	}
+
+	for _, body := range []string{
+		"client_id=c&client_secret=s&grant_type=client_credentials&scope=a+b",
+		"client_id=c&grant_type=" + url.QueryEscape(GrantTypeJWTBearer) + "&assertion=x&resource=r1&resource=r2&tenant=t",
+		"grant_type=" + url.QueryEscape(GrantTypeTokenExchange) + "&subject_token=st&subject_token_type=stt",
+	} {
+		first := decode(body)
+		if second := decode(EncodeRequest(first)); !reflect.DeepEqual(first, second) {
+			t.Errorf("round trip of %s: expected %+v, got %+v", body, first, second)
+		}
+	}
+}
+
+func TestRequestExtraHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("X-Correlation-Id"); got != "c1" {
+			t.Errorf("expected X-Correlation-Id c1, got '%s'", got)
+		}
+		if got := r.Header.Get("Content-Type"); got != "application/x-www-form-urlencoded" {
+			t.Errorf("extra header must not override content type, got '%s'", got)
+		}
+		req, err := DecodeRequestBody(r)
+		if err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		if got := req.Extra.Get("tenant_id"); got != "t1" {
+			t.Errorf("expected tenant_id t1, got '%s'", got)
+		}
+		w.Write([]byte(EncodeResponseBody("at", "", 60)))
+	}))
+	defer server.Close()
+
+	_, err := SendRequest(context.TODO(), RequestOptions{
+		TokenURL:    server.URL,
+		ExtraParams: url.Values{"tenant_id": {"t1"}},
+		ExtraHeaders: http.Header{
+			"X-Correlation-Id": {"c1"},
+			"Content-Type":     {"text/plain"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("send request: %v", err)
+	}
+}
+
+func TestDecodeResponseBodyWellKnownFields(t *testing.T) {
+	data := []byte(`{"access_token":"at","token_type":"Bearer","scope":"s","refresh_token":"rt","id_token":"it"}`)
+
+	for name, decode := range decoderTable {
+		resp, err := decode(data)
+		if err != nil {
+			t.Errorf("%s: decode: %v", name, err)
+			continue
+		}
+		if resp.RefreshToken != "rt" || resp.IDToken != "it" || resp.Scope != "s" {
+			t.Errorf("%s: unexpected response: %+v", name, resp)
+		}
+		if resp.Extra != nil {
+			t.Errorf("%s: unexpected extra: %v", name, resp.Extra)
+		}
+	}
+}
+
+func TestDecodeResponseBodyExtra(t *testing.T) {
+	data := []byte(`{"access_token":"at","token_type":"Bearer","expires_in":3600,` +
+		`"ext_expires_in":3600,"tenant":{"id":"t1"},"flags":["a","b"],"note":"x"}`)
+
+	resp, err := DecodeResponseBodyExtra(data)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	if resp.AccessToken != "at" || resp.ExpiresIn != 3600 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+
+	want := map[string]string{
+		"ext_expires_in": `3600`,
+		"tenant":         `{"id":"t1"}`,
+		"flags":          `["a","b"]`,
+		"note":           `"x"`,
+	}
+
+	if len(resp.Extra) != len(want) {
+		t.Errorf("expected %d extra fields, got %v", len(want), resp.Extra)
+	}
+	for k, v := range want {
+		if got := string(resp.Extra[k]); got != v {
+			t.Errorf("extra %s: expected %s, got %s", k, v, got)
+		}
+	}
+
+	// raw values must not alias the pooled parser buffers
+	DecodeResponseBodyExtra([]byte(`{"ext_expires_in":1111,"tenant":2,"flags":3,"note":4}`))
+	if got := string(resp.Extra["tenant"]); got != `{"id":"t1"}` {
+		t.Errorf("extra value changed after parser reuse: %s", got)
+	}
+
+	if _, err := DecodeResponseBodyExtra([]byte(`[1]`)); err == nil {
+		t.Errorf("expected error for non-object response")
+	}
+}
@@ -0,0 +1,214 @@
+package clientcredentials
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sugawarayuuta/sonnet"
+)
+
+// DefaultDiscoveryTTL is the default time metadata is cached by Discovery.
+const DefaultDiscoveryTTL = time.Hour
+
+// maxMetadataSize limits the size of metadata documents.
+const maxMetadataSize = 1 << 20
+
+// Metadata holds authorization server metadata (RFC 8414 section 2,
+// OpenID Connect Discovery 1.0 section 3).
+type Metadata struct {
+	Issuer                            string   `json:"issuer"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported,omitempty"`
+	GrantTypesSupported               []string `json:"grant_types_supported,omitempty"`
+	ScopesSupported                   []string `json:"scopes_supported,omitempty"`
+	JWKSURI                           string   `json:"jwks_uri,omitempty"`
+	RevocationEndpoint                string   `json:"revocation_endpoint,omitempty"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint,omitempty"`
+}
+
+// SupportsAuthMethod reports whether the token endpoint supports the client
+// authentication method. Per RFC 8414, when the server does not list its
+// methods, only client_secret_basic is assumed.
+func (m Metadata) SupportsAuthMethod(method string) bool {
+	if len(m.TokenEndpointAuthMethodsSupported) == 0 {
+		return method == AuthMethodClientSecretBasic
+	}
+	return slices.Contains(m.TokenEndpointAuthMethodsSupported, method)
+}
+
+// DiscoveryOptions contains options for creating a Discovery.
+type DiscoveryOptions struct {
+	// HTTPClient is optional HTTP client to use for fetching metadata.
+	// If nil, http.DefaultClient will be used.
+	HTTPClient HTTPDoer
+
+	// TTL is optional time metadata is cached.
+	// If zero, DefaultDiscoveryTTL will be used.
+	TTL time.Duration
+}
+
+// Discovery fetches and caches authorization server metadata from
+// /.well-known/oauth-authorization-server (RFC 8414) or, as a fallback,
+// /.well-known/openid-configuration (OpenID Connect Discovery).
+// Discovery is safe for concurrent use by multiple goroutines.
+type Discovery struct {
+	options DiscoveryOptions
+
+	mutex sync.Mutex
+	cache map[string]discoveryEntry
+
+	now func() time.Time
+}
+
+type discoveryEntry struct {
+	metadata Metadata
+	expiry   time.Time
+}
+
+// NewDiscovery creates a Discovery.
+func NewDiscovery(options DiscoveryOptions) *Discovery {
+	if options.HTTPClient == nil {
+		options.HTTPClient = http.DefaultClient
+	}
+	if options.TTL == 0 {
+		options.TTL = DefaultDiscoveryTTL
+	}
+	return &Discovery{
+		options: options,
+		cache:   map[string]discoveryEntry{},
+		now:     time.Now,
+	}
+}
+
+// Metadata returns the metadata for issuer, from cache if not expired.
+func (d *Discovery) Metadata(ctx context.Context, issuer string) (Metadata, error) {
+	d.mutex.Lock()
+	entry, found := d.cache[issuer]
+	d.mutex.Unlock()
+
+	if found && d.now().Before(entry.expiry) {
+		return entry.metadata, nil
+	}
+
+	metadata, err := d.fetch(ctx, issuer)
+	if err != nil {
+		return metadata, err
+	}
+
+	d.mutex.Lock()
+	d.cache[issuer] = discoveryEntry{metadata: metadata, expiry: d.now().Add(d.options.TTL)}
+	d.mutex.Unlock()
+
+	return metadata, nil
+}
+
+// RequestOptions returns a copy of options with TokenURL resolved from the
+// issuer metadata. If options.AuthMethod is empty and the server does not
+// support client_secret_post but supports client_secret_basic, AuthMethod
+// is set to AuthMethodClientSecretBasic.
+func (d *Discovery) RequestOptions(ctx context.Context, issuer string, options RequestOptions) (RequestOptions, error) {
+	metadata, err := d.Metadata(ctx, issuer)
+	if err != nil {
+		return options, err
+	}
+
+	options.TokenURL = metadata.TokenEndpoint
+
+	if options.AuthMethod == "" &&
+		!metadata.SupportsAuthMethod(AuthMethodClientSecretPost) &&
+		metadata.SupportsAuthMethod(AuthMethodClientSecretBasic) {
+		options.AuthMethod = AuthMethodClientSecretBasic
+	}
+
+	return options, nil
+}
+
+// fetch tries the RFC 8414 location first, then the OpenID Connect one.
+func (d *Discovery) fetch(ctx context.Context, issuer string) (Metadata, error) {
+	wellKnownURLs, errURL := wellKnownURLs(issuer)
+	if errURL != nil {
+		return Metadata{}, errURL
+	}
+
+	var errs []error
+	for _, u := range wellKnownURLs {
+		metadata, err := d.fetchURL(ctx, issuer, u)
+		if err == nil {
+			return metadata, nil
+		}
+		if ctx.Err() != nil {
+			return metadata, err
+		}
+		errs = append(errs, err)
+	}
+
+	return Metadata{}, fmt.Errorf("oauth2clientcredentials.Discovery: issuer %s: %w", issuer, errors.Join(errs...))
+}
+
+func (d *Discovery) fetchURL(ctx context.Context, issuer, metadataURL string) (Metadata, error) {
+	var metadata Metadata
+
+	req, errReq := http.NewRequestWithContext(ctx, "GET", metadataURL, nil)
+	if errReq != nil {
+		return metadata, errReq
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, errDo := d.options.HTTPClient.Do(req)
+	if errDo != nil {
+		return metadata, errDo
+	}
+	defer resp.Body.Close()
+
+	if err := DefaultIsStatusCodeOK(resp.StatusCode); err != nil {
+		return metadata, fmt.Errorf("%s: %w", metadataURL, err)
+	}
+
+	buf, errRead := readBody(resp.Body, resp.ContentLength, maxMetadataSize)
+	if errRead != nil {
+		return metadata, fmt.Errorf("%s: %w", metadataURL, errRead)
+	}
+	defer putBuffer(buf)
+
+	if err := sonnet.Unmarshal(buf.Bytes(), &metadata); err != nil {
+		return metadata, fmt.Errorf("%s: %w", metadataURL, err)
+	}
+
+	if strings.TrimSuffix(metadata.Issuer, "/") != strings.TrimSuffix(issuer, "/") {
+		return Metadata{}, fmt.Errorf("%s: issuer mismatch: %s", metadataURL, metadata.Issuer)
+	}
+
+	if metadata.TokenEndpoint == "" {
+		return Metadata{}, fmt.Errorf("%s: missing token_endpoint", metadataURL)
+	}
+
+	return metadata, nil
+}
+
+// wellKnownURLs returns the metadata locations for issuer.
+// RFC 8414 inserts the well-known suffix between host and path, while
+// OpenID Connect appends it to the issuer.
+func wellKnownURLs(issuer string) ([]string, error) {
+	u, err := url.Parse(issuer)
+	if err != nil {
+		return nil, fmt.Errorf("oauth2clientcredentials.Discovery: bad issuer: %w", err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("oauth2clientcredentials.Discovery: bad issuer: %s", issuer)
+	}
+
+	path := strings.TrimSuffix(u.Path, "/")
+	base := u.Scheme + "://" + u.Host
+
+	return []string{
+		base + "/.well-known/oauth-authorization-server" + path,
+		base + path + "/.well-known/openid-configuration",
+	}, nil
+}
@@ -0,0 +1,126 @@
+package clientcredentials
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWellKnownURLs(t *testing.T) {
+	tests := []struct {
+		issuer string
+		want   []string
+	}{
+		{"https://as.example.com", []string{
+			"https://as.example.com/.well-known/oauth-authorization-server",
+			"https://as.example.com/.well-known/openid-configuration",
+		}},
+		{"https://as.example.com/tenant1/", []string{
+			"https://as.example.com/.well-known/oauth-authorization-server/tenant1",
+			"https://as.example.com/tenant1/.well-known/openid-configuration",
+		}},
+	}
+	for _, tt := range tests {
+		got, err := wellKnownURLs(tt.issuer)
+		if err != nil {
+			t.Errorf("%s: %v", tt.issuer, err)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.issuer, tt.want, got)
+		}
+	}
+
+	if _, err := wellKnownURLs("not-a-url"); err == nil {
+		t.Errorf("expected error for bad issuer")
+	}
+}
+
+func TestDiscovery(t *testing.T) {
+	var count int32
+	var issuer string
+
+	mux := http.NewServeMux()
+	// only OpenID Connect discovery is available
+	mux.HandleFunc("/realm/.well-known/openid-configuration", func(w http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&count, 1)
+		w.Write([]byte(`{"issuer":"` + issuer + `","token_endpoint":"` + issuer + `/token",` +
+			`"token_endpoint_auth_methods_supported":["client_secret_basic","private_key_jwt"],` +
+			`"introspection_endpoint":"` + issuer + `/introspect","revocation_endpoint":"` + issuer + `/revoke"}`))
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	issuer = server.URL + "/realm"
+
+	d := NewDiscovery(DiscoveryOptions{TTL: time.Minute})
+	now := time.Now()
+	d.now = func() time.Time { return now }
+
+	metadata, err := d.Metadata(context.TODO(), issuer)
+	if err != nil {
+		t.Fatalf("metadata: %v", err)
+	}
+	if metadata.TokenEndpoint != issuer+"/token" {
+		t.Errorf("unexpected token_endpoint: %s", metadata.TokenEndpoint)
+	}
+	if metadata.IntrospectionEndpoint != issuer+"/introspect" {
+		t.Errorf("unexpected introspection_endpoint: %s", metadata.IntrospectionEndpoint)
+	}
+	if metadata.RevocationEndpoint != issuer+"/revoke" {
+		t.Errorf("unexpected revocation_endpoint: %s", metadata.RevocationEndpoint)
+	}
+	if !metadata.SupportsAuthMethod(AuthMethodPrivateKeyJWT) || metadata.SupportsAuthMethod(AuthMethodClientSecretPost) {
+		t.Errorf("unexpected auth methods: %v", metadata.TokenEndpointAuthMethodsSupported)
+	}
+
+	options, err := d.RequestOptions(context.TODO(), issuer, RequestOptions{ClientID: "c"})
+	if err != nil {
+		t.Fatalf("request options: %v", err)
+	}
+	if options.TokenURL != metadata.TokenEndpoint {
+		t.Errorf("expected TokenURL %s, got %s", metadata.TokenEndpoint, options.TokenURL)
+	}
+	if options.AuthMethod != AuthMethodClientSecretBasic {
+		t.Errorf("expected auth method client_secret_basic, got '%s'", options.AuthMethod)
+	}
+
+	if got := atomic.LoadInt32(&count); got != 1 {
+		t.Errorf("expected cached metadata, got %d fetches", got)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, err := d.Metadata(context.TODO(), issuer); err != nil {
+		t.Fatalf("metadata: %v", err)
+	}
+	if got := atomic.LoadInt32(&count); got != 2 {
+		t.Errorf("expected refetch after TTL, got %d fetches", got)
+	}
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(`{"issuer":"https://evil.example.com","token_endpoint":"https://evil.example.com/token"}`))
+	}))
+	defer server.Close()
+
+	d := NewDiscovery(DiscoveryOptions{})
+	if _, err := d.Metadata(context.TODO(), server.URL); err == nil {
+		t.Errorf("expected issuer mismatch error")
+	}
+}
+
+func TestMetadataDefaultAuthMethod(t *testing.T) {
+	var m Metadata
+	if !m.SupportsAuthMethod(AuthMethodClientSecretBasic) {
+		t.Errorf("expected client_secret_basic as default")
+	}
+	if m.SupportsAuthMethod(AuthMethodClientSecretPost) {
+		t.Errorf("unexpected client_secret_post as default")
+	}
+}
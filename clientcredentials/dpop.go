@@ -0,0 +1,183 @@
+package clientcredentials
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TokenTypeDPoP is the token_type of DPoP-bound access tokens (RFC 9449).
+const TokenTypeDPoP = "DPoP"
+
+// ErrorCodeUseDPoPNonce is returned by servers requiring a DPoP nonce.
+// See RFC 9449 section 8.
+const ErrorCodeUseDPoPNonce = "use_dpop_nonce"
+
+// DPoP creates DPoP proofs (RFC 9449) with a per-client P-256 key pair.
+// It remembers the nonces supplied by servers in DPoP-Nonce headers,
+// per origin, and includes them in subsequent proofs.
+// DPoP is safe for concurrent use by multiple goroutines.
+type DPoP struct {
+	key        *ecdsa.PrivateKey
+	jwk        map[string]string
+	thumbprint string
+
+	mutex  sync.Mutex
+	nonces map[string]string
+}
+
+// NewDPoP creates a DPoP with a freshly generated P-256 key pair.
+func NewDPoP() (*DPoP, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return NewDPoPWithKey(key)
+}
+
+// NewDPoPWithKey creates a DPoP using an existing P-256 key, so that tokens
+// stay usable across restarts.
+func NewDPoPWithKey(key *ecdsa.PrivateKey) (*DPoP, error) {
+	if key.Curve != elliptic.P256() {
+		return nil, fmt.Errorf("oauth2clientcredentials: dpop requires a P-256 key")
+	}
+
+	pub, errPub := key.PublicKey.ECDH()
+	if errPub != nil {
+		return nil, errPub
+	}
+	point := pub.Bytes() // 0x04 || x || y
+
+	x := base64.RawURLEncoding.EncodeToString(point[1:33])
+	y := base64.RawURLEncoding.EncodeToString(point[33:])
+
+	// RFC 7638: required members in lexicographic order, no whitespace.
+	sum := sha256.Sum256([]byte(`{"crv":"P-256","kty":"EC","x":"` + x + `","y":"` + y + `"}`))
+
+	return &DPoP{
+		key:        key,
+		jwk:        map[string]string{"kty": "EC", "crv": "P-256", "x": x, "y": y},
+		thumbprint: base64.RawURLEncoding.EncodeToString(sum[:]),
+		nonces:     map[string]string{},
+	}, nil
+}
+
+// Thumbprint returns the JWK SHA-256 thumbprint (RFC 7638) of the public
+// key, as found in the cnf.jkt claim of bound access tokens.
+func (d *DPoP) Thumbprint() string {
+	return d.thumbprint
+}
+
+// Proof creates a DPoP proof JWT for an HTTP request. If accessToken is not
+// empty, its hash is included in the ath claim, as required when presenting
+// the token to a resource server.
+func (d *DPoP) Proof(method, targetURL, accessToken string) (string, error) {
+	u, errURL := url.Parse(targetURL)
+	if errURL != nil {
+		return "", fmt.Errorf("oauth2clientcredentials: dpop: %w", errURL)
+	}
+
+	jti, errJTI := newJTI()
+	if errJTI != nil {
+		return "", errJTI
+	}
+
+	claims := jwt.MapClaims{
+		"jti": jti,
+		"htm": method,
+		"htu": htu(u),
+		"iat": time.Now().Unix(),
+	}
+
+	if accessToken != "" {
+		sum := sha256.Sum256([]byte(accessToken))
+		claims["ath"] = base64.RawURLEncoding.EncodeToString(sum[:])
+	}
+
+	if nonce := d.nonce(u); nonce != "" {
+		claims["nonce"] = nonce
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["typ"] = "dpop+jwt"
+	token.Header["jwk"] = d.jwk
+
+	str, errSign := token.SignedString(d.key)
+	if errSign != nil {
+		return "", fmt.Errorf("oauth2clientcredentials: sign dpop proof: %w", errSign)
+	}
+
+	return str, nil
+}
+
+// htu is the request URL without query and fragment (RFC 9449 section 4.2).
+func htu(u *url.URL) string {
+	v := *u
+	v.RawQuery = ""
+	v.ForceQuery = false
+	v.Fragment = ""
+	v.RawFragment = ""
+	return v.String()
+}
+
+// origin identifies the server a nonce belongs to.
+func origin(u *url.URL) string {
+	return strings.ToLower(u.Scheme + "://" + u.Host)
+}
+
+func (d *DPoP) nonce(u *url.URL) string {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.nonces[origin(u)]
+}
+
+// updateNonce records the DPoP-Nonce header, if any, of a response from u.
+// It reports whether a nonce was supplied.
+func (d *DPoP) updateNonce(u *url.URL, header http.Header) bool {
+	nonce := header.Get("DPoP-Nonce")
+	if nonce == "" {
+		return false
+	}
+	d.mutex.Lock()
+	d.nonces[origin(u)] = nonce
+	d.mutex.Unlock()
+	return true
+}
+
+// isDPoPNonceError reports whether err is a token endpoint use_dpop_nonce
+// error carrying a new nonce.
+func isDPoPNonceError(err error) bool {
+	var errResp *ErrorResponse
+	return errors.As(err, &errResp) && errResp.Code == ErrorCodeUseDPoPNonce &&
+		errResp.Header.Get("DPoP-Nonce") != ""
+}
+
+// isDPoPNonceChallenge reports whether a resource server 401 asks for a
+// DPoP nonce (RFC 9449 section 9).
+func isDPoPNonceChallenge(resp *http.Response) bool {
+	if resp.Header.Get("DPoP-Nonce") == "" {
+		return false
+	}
+	for _, challenge := range resp.Header.Values("WWW-Authenticate") {
+		if strings.Contains(challenge, ErrorCodeUseDPoPNonce) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsDPoP reports whether the token is DPoP-bound (token_type=DPoP).
+func (r Response) IsDPoP() bool {
+	return strings.EqualFold(r.TokenType, TokenTypeDPoP)
+}
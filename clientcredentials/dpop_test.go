@@ -0,0 +1,237 @@
+package clientcredentials
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// parseDPoPProof verifies a proof against the key embedded in its header.
+func parseDPoPProof(t *testing.T, proof string) (jwt.MapClaims, map[string]any) {
+	t.Helper()
+
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(proof, claims, func(token *jwt.Token) (any, error) {
+		jwk := token.Header["jwk"].(map[string]any)
+		x, _ := base64.RawURLEncoding.DecodeString(jwk["x"].(string))
+		y, _ := base64.RawURLEncoding.DecodeString(jwk["y"].(string))
+		return &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}, nil
+	}, jwt.WithValidMethods([]string{"ES256"}))
+	if err != nil {
+		t.Fatalf("parse dpop proof: %v", err)
+	}
+
+	return claims, token.Header
+}
+
+func TestDPoPProof(t *testing.T) {
+	dpop, err := NewDPoP()
+	if err != nil {
+		t.Fatalf("new dpop: %v", err)
+	}
+
+	proof, err := dpop.Proof("GET", "https://api.example.com/v1/items?page=2#top", "access-token")
+	if err != nil {
+		t.Fatalf("proof: %v", err)
+	}
+
+	claims, header := parseDPoPProof(t, proof)
+
+	if header["typ"] != "dpop+jwt" {
+		t.Errorf("unexpected typ: %v", header["typ"])
+	}
+	if claims["htm"] != "GET" {
+		t.Errorf("unexpected htm: %v", claims["htm"])
+	}
+	if claims["htu"] != "https://api.example.com/v1/items" {
+		t.Errorf("unexpected htu: %v", claims["htu"])
+	}
+	if _, found := claims["iat"]; !found {
+		t.Errorf("missing iat")
+	}
+	if claims["jti"] == "" {
+		t.Errorf("missing jti")
+	}
+	sum := sha256.Sum256([]byte("access-token"))
+	if claims["ath"] != base64.RawURLEncoding.EncodeToString(sum[:]) {
+		t.Errorf("unexpected ath: %v", claims["ath"])
+	}
+	if _, found := claims["nonce"]; found {
+		t.Errorf("unexpected nonce")
+	}
+
+	tokenProof, err := dpop.Proof("POST", "https://as.example.com/token", "")
+	if err != nil {
+		t.Fatalf("proof: %v", err)
+	}
+	if claims, _ := parseDPoPProof(t, tokenProof); claims["ath"] != nil {
+		t.Errorf("unexpected ath for token request: %v", claims["ath"])
+	}
+}
+
+func TestDPoPThumbprint(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	dpop, err := NewDPoPWithKey(key)
+	if err != nil {
+		t.Fatalf("new dpop: %v", err)
+	}
+
+	again, _ := NewDPoPWithKey(key)
+	if dpop.Thumbprint() != again.Thumbprint() || len(dpop.Thumbprint()) != 43 {
+		t.Errorf("unexpected thumbprint: %s", dpop.Thumbprint())
+	}
+
+	other, _ := NewDPoP()
+	if other.Thumbprint() == dpop.Thumbprint() {
+		t.Errorf("different keys with same thumbprint")
+	}
+
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if _, err := NewDPoPWithKey(p384); err == nil {
+		t.Errorf("expected error for P-384 key")
+	}
+}
+
+func TestSendRequestDPoPNonce(t *testing.T) {
+	var count atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count.Add(1)
+
+		claims, _ := parseDPoPProof(t, r.Header.Get("DPoP"))
+		if claims["htm"] != "POST" {
+			t.Errorf("unexpected htm: %v", claims["htm"])
+		}
+
+		if claims["nonce"] != "server-nonce" {
+			w.Header().Set("DPoP-Nonce", "server-nonce")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"use_dpop_nonce"}`))
+			return
+		}
+
+		w.Write([]byte(`{"access_token":"bound","token_type":"DPoP","expires_in":60}`))
+	}))
+	defer server.Close()
+
+	dpop, _ := NewDPoP()
+
+	resp, err := SendRequest(context.TODO(), RequestOptions{
+		TokenURL: server.URL,
+		ClientID: "id",
+		DPoP:     dpop,
+	})
+	if err != nil {
+		t.Fatalf("send request: %v", err)
+	}
+	if !resp.IsDPoP() {
+		t.Errorf("expected DPoP token, got %s", resp.TokenType)
+	}
+	if count.Load() != 2 {
+		t.Errorf("expected 2 requests, got %d", count.Load())
+	}
+
+	// the nonce is remembered
+	count.Store(0)
+	if _, err := SendRequest(context.TODO(), RequestOptions{TokenURL: server.URL, DPoP: dpop}); err != nil {
+		t.Fatalf("send request: %v", err)
+	}
+	if count.Load() != 1 {
+		t.Errorf("expected 1 request, got %d", count.Load())
+	}
+}
+
+func TestTransportDPoP(t *testing.T) {
+	var tokenCount atomic.Int32
+
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenCount.Add(1)
+		w.Write([]byte(`{"access_token":"bound","token_type":"DPoP","expires_in":60}`))
+	}))
+	defer tokenServer.Close()
+
+	var apiCount atomic.Int32
+
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiCount.Add(1)
+
+		if r.Header.Get("Authorization") != "DPoP bound" {
+			t.Errorf("unexpected authorization: %s", r.Header.Get("Authorization"))
+		}
+
+		claims, _ := parseDPoPProof(t, r.Header.Get("DPoP"))
+		sum := sha256.Sum256([]byte("bound"))
+		if claims["ath"] != base64.RawURLEncoding.EncodeToString(sum[:]) {
+			t.Errorf("unexpected ath: %v", claims["ath"])
+		}
+		if !strings.HasSuffix(claims["htu"].(string), "/resource") {
+			t.Errorf("unexpected htu: %v", claims["htu"])
+		}
+
+		if claims["nonce"] != "api-nonce" {
+			w.Header().Set("DPoP-Nonce", "api-nonce")
+			w.Header().Set("WWW-Authenticate", `DPoP error="use_dpop_nonce"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.Write([]byte("ok"))
+	}))
+	defer apiServer.Close()
+
+	dpop, _ := NewDPoP()
+
+	client := NewHTTPClient(NewTokenSource(TokenSourceOptions{
+		RequestOptions: RequestOptions{TokenURL: tokenServer.URL, DPoP: dpop},
+	}))
+
+	resp, err := client.Get(apiServer.URL + "/resource?x=1")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+	if apiCount.Load() != 2 {
+		t.Errorf("expected 2 api requests, got %d", apiCount.Load())
+	}
+	if tokenCount.Load() != 1 {
+		t.Errorf("nonce challenge should not refetch the token, got %d fetches", tokenCount.Load())
+	}
+}
+
+func TestTransportDPoPWithoutKey(t *testing.T) {
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"access_token":"bound","token_type":"dpop","expires_in":60}`))
+	}))
+	defer tokenServer.Close()
+
+	client := NewHTTPClient(NewTokenSource(TokenSourceOptions{
+		RequestOptions: RequestOptions{TokenURL: tokenServer.URL},
+	}))
+
+	if _, err := client.Get(tokenServer.URL); err == nil {
+		t.Errorf("expected error for DPoP token without DPoP key")
+	}
+}
@@ -0,0 +1,240 @@
+package clientcredentials
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultEjectionThreshold is the default number of consecutive failures
+// that ejects an endpoint from an EndpointPool.
+const DefaultEjectionThreshold = 3
+
+// DefaultEjectionTime is the default time an ejected endpoint is avoided.
+const DefaultEjectionTime = 30 * time.Second
+
+// Endpoint is a token endpoint in an EndpointPool.
+type Endpoint struct {
+	// URL is the token endpoint URL.
+	URL string
+
+	// Weight is optional relative share of requests sent to the endpoint
+	// first. If all weights are zero, endpoints are tried in order, the
+	// first one being the primary.
+	Weight int
+}
+
+// EndpointPoolOptions contains options for creating an EndpointPool.
+type EndpointPoolOptions struct {
+	// Endpoints lists the token endpoints.
+	Endpoints []Endpoint
+
+	// EjectionThreshold is optional number of consecutive failures that
+	// ejects an endpoint. If zero, DefaultEjectionThreshold will be used.
+	EjectionThreshold int
+
+	// EjectionTime is optional time an ejected endpoint is avoided before
+	// it is tried again. If zero, DefaultEjectionTime will be used.
+	EjectionTime time.Duration
+}
+
+// EndpointPool fails over token requests across multiple token endpoints,
+// for example regional identity provider clusters.
+//
+// Each request goes to the first healthy endpoint, in order or chosen by
+// weight, and fails over to the next one on transport errors, like DNS
+// failures or timeouts, or 5xx responses. Endpoints failing EjectionThreshold times in a row are
+// passively ejected for EjectionTime, after which they receive traffic
+// again, so requests return to the primary once it recovers. Ejected
+// endpoints are still tried as a last resort.
+// EndpointPool is safe for concurrent use by multiple goroutines.
+type EndpointPool struct {
+	options  EndpointPoolOptions
+	weighted bool
+
+	mutex  sync.Mutex
+	health []endpointHealth
+
+	now func() time.Time
+}
+
+type endpointHealth struct {
+	failures     int
+	ejectedUntil time.Time
+}
+
+// NewEndpointPool creates an EndpointPool.
+func NewEndpointPool(options EndpointPoolOptions) *EndpointPool {
+	if options.EjectionThreshold == 0 {
+		options.EjectionThreshold = DefaultEjectionThreshold
+	}
+	if options.EjectionTime == 0 {
+		options.EjectionTime = DefaultEjectionTime
+	}
+	pool := &EndpointPool{
+		options: options,
+		health:  make([]endpointHealth, len(options.Endpoints)),
+		now:     time.Now,
+	}
+	for _, e := range options.Endpoints {
+		if e.Weight > 0 {
+			pool.weighted = true
+		}
+	}
+	return pool
+}
+
+// Ejected reports whether the endpoint with url is currently ejected.
+func (p *EndpointPool) Ejected(url string) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	now := p.now()
+	for i, e := range p.options.Endpoints {
+		if e.URL == url {
+			return now.Before(p.health[i].ejectedUntil)
+		}
+	}
+	return false
+}
+
+// key identifies the pool endpoints for token caching.
+func (p *EndpointPool) key() string {
+	urls := make([]string, len(p.options.Endpoints))
+	for i, e := range p.options.Endpoints {
+		urls[i] = e.URL
+	}
+	return strings.Join(urls, " ")
+}
+
+// order returns the endpoint indexes to try: healthy endpoints first,
+// the first one picked by weight if weighted, then ejected ones.
+func (p *EndpointPool) order() []int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	now := p.now()
+
+	var healthy, ejected []int
+	for i := range p.options.Endpoints {
+		if now.Before(p.health[i].ejectedUntil) {
+			ejected = append(ejected, i)
+		} else {
+			healthy = append(healthy, i)
+		}
+	}
+
+	if p.weighted {
+		if first := p.pickWeighted(healthy); first > 0 {
+			healthy[0], healthy[first] = healthy[first], healthy[0]
+		}
+	}
+
+	return append(healthy, ejected...)
+}
+
+// pickWeighted returns the position in candidates of a random endpoint
+// chosen by weight.
+func (p *EndpointPool) pickWeighted(candidates []int) int {
+	var total int
+	for _, i := range candidates {
+		total += max(p.options.Endpoints[i].Weight, 0)
+	}
+	if total == 0 {
+		return 0
+	}
+	n := rand.IntN(total)
+	for pos, i := range candidates {
+		n -= max(p.options.Endpoints[i].Weight, 0)
+		if n < 0 {
+			return pos
+		}
+	}
+	return 0
+}
+
+// report records the outcome of a request to endpoint i.
+func (p *EndpointPool) report(i int, failed bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	h := &p.health[i]
+	if !failed {
+		*h = endpointHealth{}
+		return
+	}
+	h.failures++
+	if h.failures >= p.options.EjectionThreshold {
+		h.failures = 0
+		h.ejectedUntil = p.now().Add(p.options.EjectionTime)
+	}
+}
+
+// send sends the token request, failing over across the endpoints.
+// options.TokenURL is replaced by each endpoint URL.
+func (p *EndpointPool) send(ctx context.Context, options RequestOptions, req tokenRequest) (Response, error) {
+	order := p.order()
+	if len(order) == 0 {
+		return Response{}, errors.New("oauth2clientcredentials: endpoint pool has no endpoints")
+	}
+
+	var resp Response
+	var err error
+
+	for _, i := range order {
+		options.TokenURL = p.options.Endpoints[i].URL
+
+		resp, err = sendTokenRequestAttempt(ctx, options, req)
+
+		if ctx.Err() != nil {
+			return resp, err // caller gave up, outcome unknown
+		}
+
+		failed := isFailoverError(err)
+		p.report(i, failed)
+
+		if !failed {
+			return resp, err
+		}
+	}
+
+	if len(order) == 1 {
+		return resp, err
+	}
+
+	return resp, fmt.Errorf("oauth2clientcredentials: all %d token endpoints failed, last: %w", len(order), err)
+}
+
+// isFailoverError reports transport errors and 5xx responses, which suggest
+// another endpoint may succeed.
+func isFailoverError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var errResp *ErrorResponse
+	if errors.As(err, &errResp) {
+		return errResp.StatusCode >= 500
+	}
+	return isTransportError(err)
+}
+
+// isTransportError reports errors reaching the token endpoint, like DNS
+// failures, timeouts or refused connections, as opposed to errors building
+// the request or decoding the response. Callers must check their own
+// context first, since cancellation is reported as a transport error too.
+func isTransportError(err error) bool {
+	var urlErr *url.Error
+	return errors.As(err, &urlErr) || DefaultIsRetryableError(err)
+}
+
+// tokenURLKey identifies the token endpoints of options for token caching.
+func tokenURLKey(options RequestOptions) string {
+	if options.Endpoints != nil {
+		return options.Endpoints.key()
+	}
+	return options.TokenURL
+}
@@ -0,0 +1,188 @@
+package clientcredentials
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newEndpointServer serves tokens named after the endpoint, or fails with
+// the status stored in *status when not zero.
+func newEndpointServer(t *testing.T, name string, status *atomic.Int32, count *atomic.Int32) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count.Add(1)
+		if code := int(status.Load()); code != 0 {
+			w.WriteHeader(code)
+			w.Write([]byte(`{"error":"server_error"}`))
+			return
+		}
+		w.Write([]byte(`{"access_token":"` + name + `","token_type":"Bearer"}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestEndpointPoolFailover(t *testing.T) {
+	var primaryStatus, primaryCount, secondaryStatus, secondaryCount atomic.Int32
+
+	primary := newEndpointServer(t, "primary", &primaryStatus, &primaryCount)
+	secondary := newEndpointServer(t, "secondary", &secondaryStatus, &secondaryCount)
+
+	pool := NewEndpointPool(EndpointPoolOptions{
+		Endpoints:         []Endpoint{{URL: primary.URL}, {URL: secondary.URL}},
+		EjectionThreshold: 2,
+		EjectionTime:      time.Minute,
+	})
+	now := time.Now()
+	pool.now = func() time.Time { return now }
+
+	options := RequestOptions{Endpoints: pool, ClientID: "id"}
+
+	send := func() string {
+		t.Helper()
+		resp, err := SendRequest(context.TODO(), options)
+		if err != nil {
+			t.Fatalf("send request: %v", err)
+		}
+		return resp.AccessToken
+	}
+
+	if token := send(); token != "primary" {
+		t.Errorf("expected primary, got %s", token)
+	}
+
+	// primary down: fail over
+	primaryStatus.Store(http.StatusServiceUnavailable)
+	for range 2 {
+		if token := send(); token != "secondary" {
+			t.Errorf("expected secondary, got %s", token)
+		}
+	}
+	if !pool.Ejected(primary.URL) {
+		t.Fatalf("expected primary ejected")
+	}
+
+	// ejected primary is skipped
+	primaryCount.Store(0)
+	send()
+	if primaryCount.Load() != 0 {
+		t.Errorf("ejected primary contacted %d times", primaryCount.Load())
+	}
+
+	// back to the primary after the ejection time
+	primaryStatus.Store(0)
+	now = now.Add(time.Minute)
+	if token := send(); token != "primary" {
+		t.Errorf("expected return to primary, got %s", token)
+	}
+}
+
+func TestEndpointPoolFailoverTransportError(t *testing.T) {
+	var secondaryStatus, secondaryCount atomic.Int32
+	secondary := newEndpointServer(t, "secondary", &secondaryStatus, &secondaryCount)
+
+	release := make(chan struct{})
+	hanging := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		<-release
+	}))
+	defer hanging.Close()
+	defer close(release)
+
+	tests := []struct {
+		name    string
+		primary string
+	}{
+		{"unresolvable host", "http://idp-primary.invalid/token"},
+		{"client timeout", hanging.URL},
+	}
+	for _, tt := range tests {
+		pool := NewEndpointPool(EndpointPoolOptions{
+			Endpoints: []Endpoint{{URL: tt.primary}, {URL: secondary.URL}},
+		})
+		resp, err := SendRequest(context.TODO(), RequestOptions{
+			Endpoints:  pool,
+			HTTPClient: &http.Client{Timeout: 100 * time.Millisecond},
+		})
+		if err != nil {
+			t.Errorf("%s: expected failover, got %v", tt.name, err)
+			continue
+		}
+		if resp.AccessToken != "secondary" {
+			t.Errorf("%s: expected secondary, got %s", tt.name, resp.AccessToken)
+		}
+	}
+}
+
+func TestEndpointPoolNoFailoverOnOAuth2Error(t *testing.T) {
+	var primaryStatus, primaryCount, secondaryStatus, secondaryCount atomic.Int32
+
+	primary := newEndpointServer(t, "primary", &primaryStatus, &primaryCount)
+	secondary := newEndpointServer(t, "secondary", &secondaryStatus, &secondaryCount)
+
+	primaryStatus.Store(http.StatusBadRequest)
+
+	pool := NewEndpointPool(EndpointPoolOptions{
+		Endpoints: []Endpoint{{URL: primary.URL}, {URL: secondary.URL}},
+	})
+
+	_, err := SendRequest(context.TODO(), RequestOptions{Endpoints: pool})
+
+	var errResp *ErrorResponse
+	if !errors.As(err, &errResp) || errResp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 error response, got %v", err)
+	}
+	if secondaryCount.Load() != 0 {
+		t.Errorf("unexpected failover on 400")
+	}
+}
+
+func TestEndpointPoolAllFailed(t *testing.T) {
+	var primaryStatus, primaryCount, secondaryStatus, secondaryCount atomic.Int32
+
+	primary := newEndpointServer(t, "primary", &primaryStatus, &primaryCount)
+	secondary := newEndpointServer(t, "secondary", &secondaryStatus, &secondaryCount)
+
+	primaryStatus.Store(http.StatusInternalServerError)
+	secondaryStatus.Store(http.StatusBadGateway)
+
+	pool := NewEndpointPool(EndpointPoolOptions{
+		Endpoints: []Endpoint{{URL: primary.URL}, {URL: secondary.URL}},
+	})
+
+	_, err := SendRequest(context.TODO(), RequestOptions{Endpoints: pool})
+
+	var errResp *ErrorResponse
+	if !errors.As(err, &errResp) || errResp.StatusCode != http.StatusBadGateway {
+		t.Errorf("expected last endpoint error, got %v", err)
+	}
+	if primaryCount.Load() != 1 || secondaryCount.Load() != 1 {
+		t.Errorf("expected one request per endpoint, got %d and %d", primaryCount.Load(), secondaryCount.Load())
+	}
+}
+
+func TestEndpointPoolWeighted(t *testing.T) {
+	pool := NewEndpointPool(EndpointPoolOptions{
+		Endpoints: []Endpoint{{URL: "a", Weight: 0}, {URL: "b", Weight: 1}, {URL: "c", Weight: 3}},
+	})
+
+	firsts := map[int]int{}
+	for range 1000 {
+		order := pool.order()
+		if len(order) != 3 {
+			t.Fatalf("expected 3 endpoints, got %v", order)
+		}
+		firsts[order[0]]++
+	}
+
+	if firsts[0] != 0 {
+		t.Errorf("zero weight endpoint picked first %d times", firsts[0])
+	}
+	if firsts[2] < firsts[1] {
+		t.Errorf("expected heavier endpoint picked more often: %v", firsts)
+	}
+}
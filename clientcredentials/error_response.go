@@ -0,0 +1,88 @@
+package clientcredentials
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/valyala/fastjson"
+)
+
+// Error codes defined by RFC 6749 section 5.2.
+const (
+	ErrorCodeInvalidRequest       = "invalid_request"
+	ErrorCodeInvalidClient        = "invalid_client"
+	ErrorCodeInvalidGrant         = "invalid_grant"
+	ErrorCodeUnauthorizedClient   = "unauthorized_client"
+	ErrorCodeUnsupportedGrantType = "unsupported_grant_type"
+	ErrorCodeInvalidScope         = "invalid_scope"
+)
+
+// ErrorResponse is the error returned when the token endpoint replies with
+// a status code rejected by RequestOptions.IsStatusCodeOK.
+// Use errors.As to retrieve it.
+//
+// If the body is an RFC 6749 section 5.2 error response, Code, Description
+// and URI are filled; otherwise they are empty (for example, a proxy HTML
+// error page) and Body holds the raw response.
+type ErrorResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+
+	Code        string // error
+	Description string // error_description
+	URI         string // error_uri
+
+	// Err is the error returned by IsStatusCodeOK.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *ErrorResponse) Error() string {
+	if e.Code == "" {
+		return e.Err.Error()
+	}
+	var b strings.Builder
+	b.WriteString(e.Err.Error())
+	b.WriteString(": error=")
+	b.WriteString(e.Code)
+	if e.Description != "" {
+		b.WriteString(" error_description=")
+		b.WriteString(e.Description)
+	}
+	if e.URI != "" {
+		b.WriteString(" error_uri=")
+		b.WriteString(e.URI)
+	}
+	return b.String()
+}
+
+// Unwrap returns the error returned by IsStatusCodeOK.
+func (e *ErrorResponse) Unwrap() error {
+	return e.Err
+}
+
+// newErrorResponse builds an ErrorResponse, parsing body as an RFC 6749
+// section 5.2 error response when possible.
+func newErrorResponse(resp *http.Response, body []byte, err error) *ErrorResponse {
+	e := &ErrorResponse{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       body,
+		Err:        err,
+	}
+
+	p := parserPool.Get().(*fastjson.Parser)
+	defer parserPool.Put(p)
+
+	v, errParse := p.ParseBytes(body)
+	if errParse != nil {
+		return e
+	}
+
+	e.Code = string(v.GetStringBytes("error"))
+	e.Description = string(v.GetStringBytes("error_description"))
+	e.URI = string(v.GetStringBytes("error_uri"))
+
+	return e
+}
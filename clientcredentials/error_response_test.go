@@ -0,0 +1,126 @@
+package clientcredentials
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestErrorResponse(t *testing.T) {
+	tests := []struct {
+		name            string
+		status          int
+		contentType     string
+		body            string
+		wantCode        string
+		wantDescription string
+		wantURI         string
+	}{
+		{
+			name:            "invalid_client",
+			status:          http.StatusUnauthorized,
+			contentType:     "application/json",
+			body:            `{"error":"invalid_client","error_description":"bad secret","error_uri":"https://example.com/err"}`,
+			wantCode:        ErrorCodeInvalidClient,
+			wantDescription: "bad secret",
+			wantURI:         "https://example.com/err",
+		},
+		{
+			name:        "invalid_scope",
+			status:      http.StatusBadRequest,
+			contentType: "application/json",
+			body:        `{"error":"invalid_scope"}`,
+			wantCode:    ErrorCodeInvalidScope,
+		},
+		{
+			name:        "proxy html page",
+			status:      http.StatusBadGateway,
+			contentType: "text/html",
+			body:        `<html><body>502 Bad Gateway</body></html>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.Header().Set("X-Request-Id", "req1")
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			_, err := SendRequest(context.TODO(), RequestOptions{TokenURL: server.URL})
+
+			var errResp *ErrorResponse
+			if !errors.As(err, &errResp) {
+				t.Fatalf("expected *ErrorResponse, got %T: %v", err, err)
+			}
+
+			if errResp.StatusCode != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, errResp.StatusCode)
+			}
+			if errResp.Code != tt.wantCode {
+				t.Errorf("expected error '%s', got '%s'", tt.wantCode, errResp.Code)
+			}
+			if errResp.Description != tt.wantDescription {
+				t.Errorf("expected error_description '%s', got '%s'", tt.wantDescription, errResp.Description)
+			}
+			if errResp.URI != tt.wantURI {
+				t.Errorf("expected error_uri '%s', got '%s'", tt.wantURI, errResp.URI)
+			}
+			if string(errResp.Body) != tt.body {
+				t.Errorf("expected body '%s', got '%s'", tt.body, errResp.Body)
+			}
+			if errResp.Header.Get("X-Request-Id") != "req1" {
+				t.Errorf("expected response headers")
+			}
+			if !strings.Contains(err.Error(), "status code out of range") {
+				t.Errorf("expected status error in message: %v", err)
+			}
+			if tt.wantCode != "" && !strings.Contains(err.Error(), tt.wantCode) {
+				t.Errorf("expected error code in message: %v", err)
+			}
+		})
+	}
+}
+
+func TestErrorResponseCaller(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid_grant"}`))
+	}))
+	defer server.Close()
+
+	ro := RequestOptions{TokenURL: server.URL}
+
+	tests := []struct {
+		caller string
+		send   func() error
+	}{
+		{"SendRequest", func() error {
+			_, err := SendRequest(context.TODO(), ro)
+			return err
+		}},
+		{"SendTokenExchange", func() error {
+			_, err := SendTokenExchange(context.TODO(), TokenExchangeOptions{
+				RequestOptions: ro, SubjectToken: "t", SubjectTokenType: TokenTypeJWT,
+			})
+			return err
+		}},
+		{"SendJWTBearer", func() error {
+			_, err := SendJWTBearer(context.TODO(), JWTBearerOptions{RequestOptions: ro, Assertion: "a"})
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		err := tt.send()
+		prefix := "oauth2clientcredentials." + tt.caller + " error:"
+		if err == nil || !strings.HasPrefix(err.Error(), prefix) {
+			t.Errorf("expected error prefixed with %q, got %v", prefix, err)
+		}
+	}
+}
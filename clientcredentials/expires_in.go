@@ -0,0 +1,84 @@
+package clientcredentials
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/valyala/fastjson"
+)
+
+// All decoders accept expires_in as an integer, a float or a numeric
+// string. A missing or null expires_in decodes as zero; anything else is
+// reported as an error instead of silently decoding as zero. Values out of
+// the int32 range are rejected on every path, so expiry times never overflow.
+
+// expiresInFromInt range checks an integer expires_in.
+func expiresInFromInt(i int64) (int, error) {
+	if i > math.MaxInt32 || i < math.MinInt32 {
+		return 0, fmt.Errorf("expires_in out of range: %d", i)
+	}
+	return int(i), nil
+}
+
+// expiresInFromFloat converts a float expires_in, truncating fractions.
+func expiresInFromFloat(f float64) (int, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) || f > math.MaxInt32 || f < math.MinInt32 {
+		return 0, fmt.Errorf("expires_in out of range: %v", f)
+	}
+	return int(f), nil
+}
+
+// expiresInFromString converts a numeric string expires_in.
+func expiresInFromString(s string) (int, error) {
+	if i, errInt := strconv.ParseInt(s, 10, 64); errInt == nil {
+		return expiresInFromInt(i)
+	}
+	f, errFloat := strconv.ParseFloat(s, 64)
+	if errFloat != nil {
+		return 0, fmt.Errorf("error converting expires_in field from string='%s' to int: %v", s, errFloat)
+	}
+	return expiresInFromFloat(f)
+}
+
+// expiresInFromFastJSON decodes the expires_in value e, which is nil
+// when the field is missing. The integer path does not allocate.
+func expiresInFromFastJSON(e *fastjson.Value) (int, error) {
+	if e == nil {
+		return 0, nil
+	}
+	switch e.Type() {
+	case fastjson.TypeNull:
+		return 0, nil
+	case fastjson.TypeNumber:
+		if i, err := e.Int64(); err == nil {
+			return expiresInFromInt(i)
+		}
+		f, err := e.Float64()
+		if err != nil {
+			return 0, err
+		}
+		return expiresInFromFloat(f)
+	case fastjson.TypeString:
+		return expiresInFromString(string(e.GetStringBytes()))
+	}
+	return 0, fmt.Errorf("unexpected type %s for expires_in field in token response", e.Type())
+}
+
+// expiresInValue decodes a lenient expires_in with sonnet.
+type expiresInValue int
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (e *expiresInValue) UnmarshalJSON(data []byte) error {
+	p := parserPool.Get().(*fastjson.Parser)
+	defer parserPool.Put(p)
+
+	v, err := p.ParseBytes(data)
+	if err != nil {
+		return err
+	}
+
+	i, err := expiresInFromFastJSON(v)
+	*e = expiresInValue(i)
+	return err
+}
@@ -0,0 +1,56 @@
+package clientcredentials
+
+import (
+	"testing"
+)
+
+type expiresInTest struct {
+	name    string
+	value   string // raw JSON, empty means missing
+	want    int
+	wantErr bool
+}
+
+var expiresInTests = []expiresInTest{
+	{"missing", "", 0, false},
+	{"null", `null`, 0, false},
+	{"integer", `3600`, 3600, false},
+	{"float", `3600.0`, 3600, false},
+	{"float fraction", `3600.9`, 3600, false},
+	{"exponent", `3.6e3`, 3600, false},
+	{"string", `"3600"`, 3600, false},
+	{"float string", `"3600.5"`, 3600, false},
+	{"negative", `-1`, -1, false},
+	{"empty string", `""`, 0, true},
+	{"broken string", `"soon"`, 0, true},
+	{"bool", `true`, 0, true},
+	{"object", `{}`, 0, true},
+	{"array", `[3600]`, 0, true},
+	{"huge", `1e300`, 0, true},
+	{"huge integer", `99999999999`, 0, true},
+	{"huge negative integer", `-99999999999`, 0, true},
+	{"huge string", `"99999999999"`, 0, true},
+	{"max int32", `2147483647`, 2147483647, false},
+	{"max int32 string", `"2147483647"`, 2147483647, false},
+}
+
+func TestExpiresInDecoders(t *testing.T) {
+	for decoderName, decode := range decoderTable {
+		for _, tt := range expiresInTests {
+			data := `{"access_token":"at"`
+			if tt.value != "" {
+				data += `,"expires_in":` + tt.value
+			}
+			data += `}`
+
+			resp, err := decode([]byte(data))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%s: %s: expected error=%t, got %v", decoderName, tt.name, tt.wantErr, err)
+				continue
+			}
+			if !tt.wantErr && resp.ExpiresIn != tt.want {
+				t.Errorf("%s: %s: expected expires_in=%d, got %d", decoderName, tt.name, tt.want, resp.ExpiresIn)
+			}
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package clientcredentials
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sugawarayuuta/sonnet"
+)
+
+// TokenCache is a persistent token store shared by TokenSource instances,
+// possibly in different processes.
+type TokenCache interface {
+	// Lock acquires an exclusive lock on the cache, waiting until it is
+	// available or ctx is done. The returned function releases the lock.
+	Lock(ctx context.Context) (unlock func(), err error)
+
+	// Load returns the stored token. found is false if there is none.
+	Load() (token CachedToken, found bool, err error)
+
+	// Store saves the token.
+	Store(token CachedToken) error
+}
+
+// CachedToken is a token response with its absolute expiry.
+type CachedToken struct {
+	Response Response  `json:"response"`
+	Expiry   time.Time `json:"expiry"`
+}
+
+// fileCacheMagic identifies the file format version.
+const fileCacheMagic = "occ1"
+
+const (
+	fileCacheSaltSize = 16
+	fileCacheKeySize  = 32
+)
+
+// lockPollInterval is how often a busy lock is retried.
+const lockPollInterval = 50 * time.Millisecond
+
+// FileCache is a TokenCache storing the token in a file encrypted with
+// AES-GCM, using a key derived from the client secret with HKDF-SHA256.
+// Concurrent processes are serialized with a lock file next to it.
+type FileCache struct {
+	path   string
+	secret []byte
+}
+
+// NewFileCache creates a FileCache at path, encrypted with a key derived
+// from clientSecret.
+func NewFileCache(path, clientSecret string) (*FileCache, error) {
+	if clientSecret == "" {
+		return nil, errors.New("oauth2clientcredentials.NewFileCache: empty client secret")
+	}
+	return &FileCache{path: path, secret: []byte(clientSecret)}, nil
+}
+
+// Lock acquires an exclusive lock on path + ".lock".
+func (c *FileCache) Lock(ctx context.Context) (func(), error) {
+	return lockFile(ctx, c.path+".lock")
+}
+
+// Load reads and decrypts the token. A missing file is not an error.
+func (c *FileCache) Load() (CachedToken, bool, error) {
+	var token CachedToken
+
+	data, errRead := os.ReadFile(c.path)
+	if errors.Is(errRead, fs.ErrNotExist) {
+		return token, false, nil
+	}
+	if errRead != nil {
+		return token, false, errRead
+	}
+
+	const headerSize = len(fileCacheMagic) + fileCacheSaltSize
+	if len(data) < headerSize || !bytes.HasPrefix(data, []byte(fileCacheMagic)) {
+		return token, false, fmt.Errorf("oauth2clientcredentials.FileCache: %s: bad file format", c.path)
+	}
+
+	salt := data[len(fileCacheMagic):headerSize]
+
+	aead, errAEAD := c.aead(salt)
+	if errAEAD != nil {
+		return token, false, errAEAD
+	}
+
+	sealed := data[headerSize:]
+	if len(sealed) < aead.NonceSize() {
+		return token, false, fmt.Errorf("oauth2clientcredentials.FileCache: %s: bad file format", c.path)
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+
+	plaintext, errOpen := aead.Open(nil, nonce, ciphertext, []byte(fileCacheMagic))
+	if errOpen != nil {
+		return token, false, fmt.Errorf("oauth2clientcredentials.FileCache: %s: decrypt: %w", c.path, errOpen)
+	}
+
+	if err := sonnet.Unmarshal(plaintext, &token); err != nil {
+		return token, false, fmt.Errorf("oauth2clientcredentials.FileCache: %s: %w", c.path, err)
+	}
+
+	return token, true, nil
+}
+
+// Store encrypts and atomically writes the token with mode 0600.
+func (c *FileCache) Store(token CachedToken) error {
+	plaintext, errJSON := sonnet.Marshal(token)
+	if errJSON != nil {
+		return errJSON
+	}
+
+	salt := make([]byte, fileCacheSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+
+	aead, errAEAD := c.aead(salt)
+	if errAEAD != nil {
+		return errAEAD
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	data := make([]byte, 0, len(fileCacheMagic)+len(salt)+len(nonce)+len(plaintext)+aead.Overhead())
+	data = append(data, fileCacheMagic...)
+	data = append(data, salt...)
+	data = append(data, nonce...)
+	data = aead.Seal(data, nonce, plaintext, []byte(fileCacheMagic))
+
+	tmp, errTmp := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".tmp*")
+	if errTmp != nil {
+		return errTmp
+	}
+	defer os.Remove(tmp.Name()) // no-op after successful rename
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), c.path)
+}
+
+func (c *FileCache) aead(salt []byte) (cipher.AEAD, error) {
+	key, errKey := hkdf.Key(sha256.New, c.secret, salt, "oauth2clientcredentials file cache", fileCacheKeySize)
+	if errKey != nil {
+		return nil, errKey
+	}
+	block, errBlock := aes.NewCipher(key)
+	if errBlock != nil {
+		return nil, errBlock
+	}
+	return cipher.NewGCM(block)
+}
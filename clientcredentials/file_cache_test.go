@@ -0,0 +1,179 @@
+package clientcredentials
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFileCacheRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+
+	c, err := NewFileCache(path, "secret")
+	if err != nil {
+		t.Fatalf("new file cache: %v", err)
+	}
+
+	if _, found, err := c.Load(); err != nil || found {
+		t.Fatalf("expected empty cache, got found=%t err=%v", found, err)
+	}
+
+	want := CachedToken{
+		Response: Response{AccessToken: "at", TokenType: "Bearer", ExpiresIn: 60},
+		Expiry:   time.Now().Add(time.Minute).Round(time.Second),
+	}
+
+	if err := c.Store(want); err != nil {
+		t.Fatalf("store: %v", err)
+	}
+
+	got, found, err := c.Load()
+	if err != nil || !found {
+		t.Fatalf("load: found=%t err=%v", found, err)
+	}
+	if got.Response.AccessToken != want.Response.AccessToken || !got.Expiry.Equal(want.Expiry) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read file: %v", err)
+	}
+	if bytes.Contains(data, []byte("at")) && bytes.Contains(data, []byte("Bearer")) {
+		t.Errorf("token stored in plaintext")
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+		t.Errorf("expected mode 0600, got %v", info.Mode().Perm())
+	}
+
+	other, _ := NewFileCache(path, "rotated")
+	if _, _, err := other.Load(); err == nil {
+		t.Errorf("expected decrypt error with different secret")
+	}
+
+	if _, err := NewFileCache(path, ""); err == nil {
+		t.Errorf("expected error for empty secret")
+	}
+}
+
+func TestFileCacheLock(t *testing.T) {
+	c, _ := NewFileCache(filepath.Join(t.TempDir(), "token"), "secret")
+
+	unlock, err := c.Lock(context.TODO())
+	if err != nil {
+		t.Fatalf("lock: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 200*time.Millisecond)
+	defer cancel()
+
+	if _, err := c.Lock(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected lock to be busy, got %v", err)
+	}
+
+	unlock()
+
+	unlock2, err := c.Lock(context.TODO())
+	if err != nil {
+		t.Fatalf("lock after unlock: %v", err)
+	}
+	unlock2()
+}
+
+func TestTokenSourceFileCache(t *testing.T) {
+	var count int32
+	server := newCountingTokenServer(t, 3600, &count)
+
+	path := filepath.Join(t.TempDir(), "token")
+
+	newSource := func() *TokenSource {
+		c, err := NewFileCache(path, "secret")
+		if err != nil {
+			t.Fatalf("new file cache: %v", err)
+		}
+		return NewTokenSource(TokenSourceOptions{
+			RequestOptions: RequestOptions{TokenURL: server.URL},
+			Cache:          c,
+		})
+	}
+
+	// two independent sources simulate two process runs
+	for range 2 {
+		tok, err := newSource().Token(context.TODO())
+		if err != nil {
+			t.Fatalf("token: %v", err)
+		}
+		if tok.AccessToken != "token1" {
+			t.Errorf("expected persisted token1, got %s", tok.AccessToken)
+		}
+	}
+
+	if got := atomic.LoadInt32(&count); got != 1 {
+		t.Errorf("expected 1 fetch, got %d", got)
+	}
+
+	ts := newSource()
+	ts.Invalidate()
+	if tok, _ := ts.Token(context.TODO()); tok.AccessToken != "token2" {
+		t.Errorf("expected token2 after invalidate, got %s", tok.AccessToken)
+	}
+
+	if tok, _ := newSource().Token(context.TODO()); tok.AccessToken != "token2" {
+		t.Errorf("expected persisted token2, got %s", tok.AccessToken)
+	}
+}
+
+func TestTokenSourceFileCacheUnusable(t *testing.T) {
+	var count int32
+	server := newCountingTokenServer(t, 3600, &count)
+
+	c, err := NewFileCache(filepath.Join(t.TempDir(), "missing", "token"), "secret")
+	if err != nil {
+		t.Fatalf("new file cache: %v", err)
+	}
+	ts := NewTokenSource(TokenSourceOptions{
+		RequestOptions: RequestOptions{TokenURL: server.URL},
+		Cache:          c,
+	})
+
+	for range 2 {
+		tok, err := ts.Token(context.TODO())
+		if err != nil {
+			t.Fatalf("expected fetch without cache, got %v", err)
+		}
+		if tok.AccessToken != "token1" {
+			t.Errorf("expected token1, got %s", tok.AccessToken)
+		}
+	}
+	if got := atomic.LoadInt32(&count); got != 1 {
+		t.Errorf("expected token kept in memory, got %d fetches", got)
+	}
+}
+
+func TestTokenSourceFileCacheExpired(t *testing.T) {
+	var count int32
+	server := newCountingTokenServer(t, 100, &count)
+
+	c, _ := NewFileCache(filepath.Join(t.TempDir(), "token"), "secret")
+
+	// persisted token within the expiry margin
+	c.Store(CachedToken{
+		Response: Response{AccessToken: "old", ExpiresIn: 100},
+		Expiry:   time.Now().Add(5 * time.Second),
+	})
+
+	ts := NewTokenSource(TokenSourceOptions{
+		RequestOptions: RequestOptions{TokenURL: server.URL},
+		ExpiryMargin:   10 * time.Second,
+		Cache:          c,
+	})
+
+	if tok, _ := ts.Token(context.TODO()); tok.AccessToken != "token1" {
+		t.Errorf("expected fresh token1, got %s", tok.AccessToken)
+	}
+}
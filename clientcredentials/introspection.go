@@ -0,0 +1,294 @@
+package clientcredentials
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/valyala/fastjson"
+)
+
+// Token type hints defined by RFC 7009 section 2.1, used by introspection.
+const (
+	TokenTypeHintAccessToken  = "access_token"
+	TokenTypeHintRefreshToken = "refresh_token"
+)
+
+// IntrospectionRequest represents a token introspection request (RFC 7662
+// section 2.1). Client authentication fields are filled as selected by
+// RequestOptions.AuthMethod.
+type IntrospectionRequest struct {
+	Token         string
+	TokenTypeHint string
+
+	ClientID            string
+	ClientSecret        string
+	ClientAssertion     string
+	ClientAssertionType string
+
+	// Extra holds additional parameters. Standard parameters are ignored.
+	Extra url.Values
+}
+
+// form converts the introspection request into the shared form encoding,
+// carrying token and token_type_hint as extra parameters.
+func (r IntrospectionRequest) form() Request {
+	extra := make(url.Values, len(r.Extra)+2)
+	for k, v := range r.Extra {
+		if k != "token" && k != "token_type_hint" {
+			extra[k] = v
+		}
+	}
+	extra.Set("token", r.Token)
+	if r.TokenTypeHint != "" {
+		extra.Set("token_type_hint", r.TokenTypeHint)
+	}
+	return Request{
+		ClientID:            r.ClientID,
+		ClientSecret:        r.ClientSecret,
+		ClientAssertion:     r.ClientAssertion,
+		ClientAssertionType: r.ClientAssertionType,
+		Extra:               extra,
+	}
+}
+
+// EncodeIntrospectionRequest encodes an introspection request body.
+func EncodeIntrospectionRequest(req IntrospectionRequest) string {
+	return EncodeRequest(req.form())
+}
+
+// DecodeIntrospectionRequest decodes an introspection request, for example
+// in a test server. Client credentials sent with HTTP Basic authentication
+// are also recognized.
+func DecodeIntrospectionRequest(r *http.Request) (IntrospectionRequest, error) {
+	form, err := DecodeRequestBody(r)
+	if err != nil {
+		return IntrospectionRequest{}, err
+	}
+
+	req := IntrospectionRequest{
+		Token:               form.Extra.Get("token"),
+		TokenTypeHint:       form.Extra.Get("token_type_hint"),
+		ClientID:            form.ClientID,
+		ClientSecret:        form.ClientSecret,
+		ClientAssertion:     form.ClientAssertion,
+		ClientAssertionType: form.ClientAssertionType,
+	}
+
+	form.Extra.Del("token")
+	form.Extra.Del("token_type_hint")
+	if len(form.Extra) > 0 {
+		req.Extra = form.Extra
+	}
+
+	return req, nil
+}
+
+// IntrospectionResponse represents a token introspection response (RFC 7662
+// section 2.2). Exp, Iat and Nbf are seconds since the Unix epoch, zero if
+// absent. Aud accepts both a single string and an array.
+type IntrospectionResponse struct {
+	Active    bool
+	Scope     string
+	ClientID  string
+	Username  string
+	TokenType string
+	Exp       int64
+	Iat       int64
+	Nbf       int64
+	Sub       string
+	Aud       []string
+	Iss       string
+	Jti       string
+
+	// Extra holds the raw JSON values of other claims, nil if none.
+	Extra map[string]json.RawMessage
+}
+
+// ExpiresAt returns the expiration time, or the zero time if exp is absent.
+func (r IntrospectionResponse) ExpiresAt() time.Time {
+	if r.Exp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(r.Exp, 0)
+}
+
+// introspectionFields are the claims mapped to dedicated fields.
+var introspectionFields = map[string]bool{
+	"active":     true,
+	"scope":      true,
+	"client_id":  true,
+	"username":   true,
+	"token_type": true,
+	"exp":        true,
+	"iat":        true,
+	"nbf":        true,
+	"sub":        true,
+	"aud":        true,
+	"iss":        true,
+	"jti":        true,
+}
+
+// DecodeIntrospectionResponse decodes an introspection response body.
+// The active member is required.
+func DecodeIntrospectionResponse(data []byte) (IntrospectionResponse, error) {
+	var resp IntrospectionResponse
+
+	p := parserPool.Get().(*fastjson.Parser)
+	defer parserPool.Put(p)
+
+	v, err := p.ParseBytes(data)
+	if err != nil {
+		return resp, err
+	}
+
+	obj, errObj := v.Object()
+	if errObj != nil {
+		return resp, errObj
+	}
+
+	active := v.Get("active")
+	if active == nil {
+		return resp, errors.New("missing active field in introspection response")
+	}
+	resp.Active, err = active.Bool()
+	if err != nil {
+		return resp, fmt.Errorf("unexpected type %s for active field in introspection response", active.Type())
+	}
+
+	if resp.Exp, err = unixTimeFromFastJSON(v, "exp"); err != nil {
+		return resp, err
+	}
+	if resp.Iat, err = unixTimeFromFastJSON(v, "iat"); err != nil {
+		return resp, err
+	}
+	if resp.Nbf, err = unixTimeFromFastJSON(v, "nbf"); err != nil {
+		return resp, err
+	}
+	if resp.Aud, err = audienceFromFastJSON(v.Get("aud")); err != nil {
+		return resp, err
+	}
+
+	resp.Scope = string(v.GetStringBytes("scope"))
+	resp.ClientID = string(v.GetStringBytes("client_id"))
+	resp.Username = string(v.GetStringBytes("username"))
+	resp.TokenType = string(v.GetStringBytes("token_type"))
+	resp.Sub = string(v.GetStringBytes("sub"))
+	resp.Iss = string(v.GetStringBytes("iss"))
+	resp.Jti = string(v.GetStringBytes("jti"))
+
+	obj.Visit(func(key []byte, value *fastjson.Value) {
+		if introspectionFields[string(key)] {
+			return
+		}
+		if resp.Extra == nil {
+			resp.Extra = map[string]json.RawMessage{}
+		}
+		// MarshalTo copies, so the value outlives the pooled parser
+		resp.Extra[string(key)] = value.MarshalTo(nil)
+	})
+
+	return resp, nil
+}
+
+// unixTimeFromFastJSON decodes a NumericDate claim, truncating fractions.
+func unixTimeFromFastJSON(v *fastjson.Value, key string) (int64, error) {
+	e := v.Get(key)
+	if e == nil || e.Type() == fastjson.TypeNull {
+		return 0, nil
+	}
+	if e.Type() != fastjson.TypeNumber {
+		return 0, fmt.Errorf("unexpected type %s for %s field in introspection response", e.Type(), key)
+	}
+	if i, err := e.Int64(); err == nil {
+		return i, nil
+	}
+	f, err := e.Float64()
+	if err != nil {
+		return 0, err
+	}
+	return int64(f), nil
+}
+
+// audienceFromFastJSON decodes aud, either a string or an array of strings.
+func audienceFromFastJSON(e *fastjson.Value) ([]string, error) {
+	if e == nil {
+		return nil, nil
+	}
+	switch e.Type() {
+	case fastjson.TypeNull:
+		return nil, nil
+	case fastjson.TypeString:
+		return []string{string(e.GetStringBytes())}, nil
+	case fastjson.TypeArray:
+		values := e.GetArray()
+		aud := make([]string, 0, len(values))
+		for _, a := range values {
+			s, err := a.StringBytes()
+			if err != nil {
+				return nil, fmt.Errorf("unexpected type %s in aud field in introspection response", a.Type())
+			}
+			aud = append(aud, string(s))
+		}
+		return aud, nil
+	}
+	return nil, fmt.Errorf("unexpected type %s for aud field in introspection response", e.Type())
+}
+
+// IntrospectionOptions contains options for sending an introspection request.
+type IntrospectionOptions struct {
+	// RequestOptions provides client authentication, HTTP client, extra
+	// parameters and headers, retry and response size settings. TokenURL,
+	// Endpoints, DPoP and DecodeResponse are not used.
+	RequestOptions RequestOptions
+
+	// IntrospectionURL is the introspection endpoint, for example from
+	// Metadata.IntrospectionEndpoint. It is required.
+	IntrospectionURL string
+
+	// Token is the token to introspect. It is required.
+	Token string
+
+	// TokenTypeHint is optional hint about the type of Token, like
+	// TokenTypeHintAccessToken.
+	TokenTypeHint string
+}
+
+// SendIntrospection sends a token introspection request (RFC 7662) and
+// returns the response. An inactive token is not an error: check
+// IntrospectionResponse.Active.
+//
+// With private_key_jwt, the client assertion audience is IntrospectionURL.
+func SendIntrospection(ctx context.Context, options IntrospectionOptions) (IntrospectionResponse, error) {
+	if options.IntrospectionURL == "" || options.Token == "" {
+		return IntrospectionResponse{}, fmt.Errorf("oauth2clientcredentials.SendIntrospection: introspection URL and token are required")
+	}
+
+	ro := options.RequestOptions
+	ro.TokenURL = options.IntrospectionURL
+	ro.Endpoints = nil
+	ro.DPoP = nil
+
+	header := ro.ExtraHeaders.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	if header.Get("Accept") == "" {
+		header.Set("Accept", "application/json")
+	}
+	ro.ExtraHeaders = header
+
+	form := IntrospectionRequest{
+		Token:         options.Token,
+		TokenTypeHint: options.TokenTypeHint,
+		Extra:         ro.ExtraParams,
+	}.form()
+
+	return sendWithPolicy(ctx, ro, func() (IntrospectionResponse, error) {
+		return postForm(ctx, ro, form, nil, "SendIntrospection", DecodeIntrospectionResponse)
+	})
+}
@@ -0,0 +1,214 @@
+package clientcredentials
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncodeIntrospectionRequest(t *testing.T) {
+	body := EncodeIntrospectionRequest(IntrospectionRequest{
+		Token:         "opaque",
+		TokenTypeHint: TokenTypeHintAccessToken,
+		ClientID:      "rs",
+		ClientSecret:  "secret",
+		Extra:         url.Values{"tenant": {"t1"}, "token": {"ignored"}},
+	})
+
+	want := "client_id=rs&client_secret=secret&tenant=t1&token=opaque&token_type_hint=access_token"
+	if body != want {
+		t.Errorf("expected %s, got %s", want, body)
+	}
+
+	r := httptest.NewRequest("POST", "/introspect", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	req, err := DecodeIntrospectionRequest(r)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	wantReq := IntrospectionRequest{
+		Token:         "opaque",
+		TokenTypeHint: TokenTypeHintAccessToken,
+		ClientID:      "rs",
+		ClientSecret:  "secret",
+		Extra:         url.Values{"tenant": {"t1"}},
+	}
+	if !reflect.DeepEqual(req, wantReq) {
+		t.Errorf("expected %+v, got %+v", wantReq, req)
+	}
+}
+
+func TestDecodeIntrospectionResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    IntrospectionResponse
+		wantErr bool
+	}{
+		{
+			name: "full",
+			input: `{"active":true,"scope":"read write","client_id":"app","username":"jdoe",
+				"token_type":"Bearer","exp":1700000000,"iat":1699996400,"nbf":1699996400,
+				"sub":"Z5O3upPC88QrAjx00dis","aud":"https://api.example.com","iss":"https://idp.example.com",
+				"jti":"abc","tenant":"t1","roles":["admin"]}`,
+			want: IntrospectionResponse{
+				Active:    true,
+				Scope:     "read write",
+				ClientID:  "app",
+				Username:  "jdoe",
+				TokenType: "Bearer",
+				Exp:       1700000000,
+				Iat:       1699996400,
+				Nbf:       1699996400,
+				Sub:       "Z5O3upPC88QrAjx00dis",
+				Aud:       []string{"https://api.example.com"},
+				Iss:       "https://idp.example.com",
+				Jti:       "abc",
+				Extra: map[string]json.RawMessage{
+					"tenant": []byte(`"t1"`),
+					"roles":  []byte(`["admin"]`),
+				},
+			},
+		},
+		{
+			name:  "inactive",
+			input: `{"active":false}`,
+			want:  IntrospectionResponse{},
+		},
+		{
+			name:  "aud array and float exp",
+			input: `{"active":true,"aud":["a","b"],"exp":1700000000.5}`,
+			want:  IntrospectionResponse{Active: true, Aud: []string{"a", "b"}, Exp: 1700000000},
+		},
+		{"missing active", `{"scope":"read"}`, IntrospectionResponse{}, true},
+		{"string active", `{"active":"true"}`, IntrospectionResponse{}, true},
+		{"string exp", `{"active":true,"exp":"soon"}`, IntrospectionResponse{}, true},
+		{"numeric aud", `{"active":true,"aud":42}`, IntrospectionResponse{}, true},
+		{"mixed aud", `{"active":true,"aud":["a",1]}`, IntrospectionResponse{}, true},
+		{"not an object", `[]`, IntrospectionResponse{}, true},
+		{"broken", `{"active":`, IntrospectionResponse{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := DecodeIntrospectionResponse([]byte(tt.input))
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if got.Extra != nil || tt.want.Extra != nil {
+			if len(got.Extra) != len(tt.want.Extra) {
+				t.Errorf("%s: expected extra %v, got %v", tt.name, tt.want.Extra, got.Extra)
+			}
+			for k, v := range tt.want.Extra {
+				if string(got.Extra[k]) != string(v) {
+					t.Errorf("%s: extra %s: expected %s, got %s", tt.name, k, v, got.Extra[k])
+				}
+			}
+			got.Extra, tt.want.Extra = nil, nil
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: expected %+v, got %+v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestIntrospectionResponseExpiresAt(t *testing.T) {
+	if !(IntrospectionResponse{}).ExpiresAt().IsZero() {
+		t.Errorf("expected zero time without exp")
+	}
+	if got := (IntrospectionResponse{Exp: 1700000000}).ExpiresAt(); !got.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("unexpected expiration: %v", got)
+	}
+}
+
+func TestSendIntrospection(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/introspect" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		req, err := DecodeIntrospectionRequest(r)
+		if err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		if req.ClientID != "rs" || req.ClientSecret != "secret" {
+			t.Errorf("unexpected client credentials: %s %s", req.ClientID, req.ClientSecret)
+		}
+		if req.TokenTypeHint != TokenTypeHintAccessToken {
+			t.Errorf("unexpected token_type_hint: %s", req.TokenTypeHint)
+		}
+		if req.Token != "opaque" {
+			w.Write([]byte(`{"active":false}`))
+			return
+		}
+		w.Write([]byte(`{"active":true,"client_id":"app","aud":["api"]}`))
+	}))
+	defer server.Close()
+
+	options := IntrospectionOptions{
+		RequestOptions: RequestOptions{
+			ClientID:     "rs",
+			ClientSecret: "secret",
+			AuthMethod:   AuthMethodClientSecretBasic,
+		},
+		IntrospectionURL: server.URL + "/introspect",
+		Token:            "opaque",
+		TokenTypeHint:    TokenTypeHintAccessToken,
+	}
+
+	resp, err := SendIntrospection(context.TODO(), options)
+	if err != nil {
+		t.Fatalf("send introspection: %v", err)
+	}
+	if !resp.Active || resp.ClientID != "app" || len(resp.Aud) != 1 || resp.Aud[0] != "api" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+
+	options.Token = "revoked"
+	resp, err = SendIntrospection(context.TODO(), options)
+	if err != nil {
+		t.Fatalf("send introspection: %v", err)
+	}
+	if resp.Active {
+		t.Errorf("expected inactive token")
+	}
+}
+
+func TestSendIntrospectionErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":"invalid_client"}`))
+	}))
+	defer server.Close()
+
+	_, err := SendIntrospection(context.TODO(), IntrospectionOptions{
+		IntrospectionURL: server.URL,
+		Token:            "opaque",
+	})
+
+	var errResp *ErrorResponse
+	if !errors.As(err, &errResp) || errResp.Code != ErrorCodeInvalidClient {
+		t.Errorf("expected invalid_client error response, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "SendIntrospection") {
+		t.Errorf("expected SendIntrospection in error: %v", err)
+	}
+
+	if _, err := SendIntrospection(context.TODO(), IntrospectionOptions{IntrospectionURL: server.URL}); err == nil {
+		t.Errorf("expected error for missing token")
+	}
+}
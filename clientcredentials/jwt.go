@@ -0,0 +1,94 @@
+package clientcredentials
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// clientAssertionLifetime is the validity of minted client assertions.
+const clientAssertionLifetime = time.Minute
+
+// newClientAssertion mints a fresh client assertion for private_key_jwt.
+// See RFC 7523 section 3.
+func newClientAssertion(options RequestOptions) (string, error) {
+	if options.PrivateKey == nil {
+		return "", fmt.Errorf("oauth2clientcredentials: private_key_jwt requires PrivateKey")
+	}
+
+	jti, errJTI := newJTI()
+	if errJTI != nil {
+		return "", errJTI
+	}
+
+	now := time.Now()
+
+	claims := jwt.MapClaims{
+		"iss": options.ClientID,
+		"sub": options.ClientID,
+		"aud": options.TokenURL,
+		"jti": jti,
+		"iat": now.Unix(),
+		"exp": now.Add(clientAssertionLifetime).Unix(),
+	}
+
+	return signJWT(options.PrivateKey, options.KeyID, claims)
+}
+
+// signJWT signs claims with key, choosing the algorithm from the key type.
+func signJWT(key crypto.Signer, keyID string, claims jwt.Claims) (string, error) {
+	method, errMethod := signingMethod(key)
+	if errMethod != nil {
+		return "", errMethod
+	}
+
+	token := jwt.NewWithClaims(method, claims)
+	if keyID != "" {
+		token.Header["kid"] = keyID
+	}
+
+	str, errSign := token.SignedString(key)
+	if errSign != nil {
+		return "", fmt.Errorf("oauth2clientcredentials: sign jwt: %w", errSign)
+	}
+
+	return str, nil
+}
+
+// signingMethod picks the JWS algorithm for a private key.
+func signingMethod(key crypto.Signer) (jwt.SigningMethod, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return jwt.SigningMethodRS256, nil
+	case *ecdsa.PrivateKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return jwt.SigningMethodES256, nil
+		case elliptic.P384():
+			return jwt.SigningMethodES384, nil
+		case elliptic.P521():
+			return jwt.SigningMethodES512, nil
+		}
+		return nil, fmt.Errorf("oauth2clientcredentials: unsupported ecdsa curve: %s", k.Curve.Params().Name)
+	case ed25519.PrivateKey:
+		return jwt.SigningMethodEdDSA, nil
+	}
+	return nil, fmt.Errorf("oauth2clientcredentials: unsupported private key type: %T", key)
+}
+
+// newJTI returns a random JWT ID.
+func newJTI() (string, error) {
+	var buf [16]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf[:]), nil
+}
@@ -0,0 +1,122 @@
+package clientcredentials
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// GrantTypeJWTBearer is the grant_type for the JWT bearer authorization
+// grant (RFC 7523 section 2.1).
+const GrantTypeJWTBearer = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+
+// DefaultJWTBearerLifetime is the default validity of minted assertions.
+const DefaultJWTBearerLifetime = 5 * time.Minute
+
+// JWTBearerOptions contains options for sending a JWT bearer grant request.
+// Either Assertion or both AssertionKey and Issuer must be set.
+type JWTBearerOptions struct {
+	// RequestOptions provides the token endpoint, optional client
+	// authentication, scope, audience, resource and HTTP settings.
+	// Empty ClientID and ClientSecret are not sent.
+	RequestOptions RequestOptions
+
+	// Assertion is optional pre-built assertion.
+	// If empty, a fresh assertion is minted for each attempt, including
+	// retries and failover to another endpoint.
+	Assertion string
+
+	// AssertionKey signs minted assertions. Supported keys are
+	// *rsa.PrivateKey, *ecdsa.PrivateKey and ed25519.PrivateKey.
+	AssertionKey crypto.Signer
+
+	// AssertionKeyID is optional kid header of minted assertions.
+	AssertionKeyID string
+
+	// Issuer is the iss claim of minted assertions, like a service account email.
+	Issuer string
+
+	// Subject is optional sub claim of minted assertions.
+	// If empty, Issuer will be used.
+	Subject string
+
+	// Audience is optional aud claim of minted assertions.
+	// If empty, the URL of the token endpoint contacted will be used:
+	// RequestOptions.TokenURL, or each URL of RequestOptions.Endpoints.
+	Audience string
+
+	// Lifetime is optional validity of minted assertions.
+	// If zero, DefaultJWTBearerLifetime will be used.
+	Lifetime time.Duration
+
+	// Claims is optional set of additional claims for minted assertions.
+	Claims map[string]any
+}
+
+// SendJWTBearer sends a JWT bearer grant request (RFC 7523 section 2.1)
+// and returns the response.
+func SendJWTBearer(ctx context.Context, options JWTBearerOptions) (Response, error) {
+	ro := options.RequestOptions
+
+	req := tokenRequest{
+		form: Request{
+			GrantType: GrantTypeJWTBearer,
+			Scope:     ro.Scope,
+			Audience:  ro.Audience,
+			Resource:  ro.Resource,
+			Extra:     ro.ExtraParams,
+			Assertion: options.Assertion,
+		},
+		caller: "SendJWTBearer",
+	}
+
+	if req.form.Assertion == "" {
+		if options.AssertionKey == nil || options.Issuer == "" {
+			return Response{}, fmt.Errorf("oauth2clientcredentials.SendJWTBearer: either Assertion or AssertionKey and Issuer are required")
+		}
+		req.mint = func(tokenURL string) (string, error) {
+			return newJWTBearerAssertion(options, tokenURL)
+		}
+	}
+
+	return sendTokenRequest(ctx, ro, req)
+}
+
+// newJWTBearerAssertion mints an assertion from options for the token
+// endpoint at tokenURL.
+func newJWTBearerAssertion(options JWTBearerOptions, tokenURL string) (string, error) {
+	jti, errJTI := newJTI()
+	if errJTI != nil {
+		return "", errJTI
+	}
+
+	subject := options.Subject
+	if subject == "" {
+		subject = options.Issuer
+	}
+	audience := options.Audience
+	if audience == "" {
+		audience = tokenURL
+	}
+	lifetime := options.Lifetime
+	if lifetime == 0 {
+		lifetime = DefaultJWTBearerLifetime
+	}
+
+	now := time.Now()
+
+	claims := jwt.MapClaims{}
+	maps.Copy(claims, options.Claims)
+	claims["iss"] = options.Issuer
+	claims["sub"] = subject
+	claims["aud"] = audience
+	claims["jti"] = jti
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(lifetime).Unix()
+
+	return signJWT(options.AssertionKey, options.AssertionKeyID, claims)
+}
@@ -0,0 +1,190 @@
+package clientcredentials
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestSendJWTBearer(t *testing.T) {
+	const issuer = "svc@example.iam"
+
+	key := newTestKeys(t)["RS256"]
+
+	var tokenURL string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, err := DecodeRequestBody(r)
+		if err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		if req.GrantType != GrantTypeJWTBearer {
+			t.Errorf("unexpected grant_type: %s", req.GrantType)
+		}
+		if r.PostForm.Has("client_id") || r.PostForm.Has("client_secret") {
+			t.Errorf("unexpected client credentials in body")
+		}
+
+		claims := jwt.MapClaims{}
+		_, err = jwt.ParseWithClaims(req.Assertion, claims, func(*jwt.Token) (any, error) {
+			return key.Public(), nil
+		}, jwt.WithIssuer(issuer), jwt.WithSubject(issuer), jwt.WithAudience(tokenURL))
+		if err != nil {
+			t.Fatalf("parse assertion: %v", err)
+		}
+		if claims["scope"] != "cloud" {
+			t.Errorf("expected extra scope claim, got %v", claims["scope"])
+		}
+
+		w.Write([]byte(EncodeResponseBody("at", "", 3600)))
+	}))
+	defer server.Close()
+
+	tokenURL = server.URL
+
+	resp, err := SendJWTBearer(context.TODO(), JWTBearerOptions{
+		RequestOptions: RequestOptions{TokenURL: tokenURL},
+		AssertionKey:   key,
+		Issuer:         issuer,
+		Claims:         map[string]any{"scope": "cloud", "iss": "ignored"},
+	})
+	if err != nil {
+		t.Fatalf("send jwt bearer: %v", err)
+	}
+	if resp.AccessToken != "at" || resp.ExpiresIn != 3600 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestSendJWTBearerPrebuiltAssertion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, err := DecodeRequestBody(r)
+		if err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		if req.Assertion != "prebuilt" {
+			t.Errorf("unexpected assertion: %s", req.Assertion)
+		}
+		if req.ClientID != "c" {
+			t.Errorf("expected client_id c, got '%s'", req.ClientID)
+		}
+		w.Write([]byte(EncodeResponseBody("at", "", 60)))
+	}))
+	defer server.Close()
+
+	_, err := SendJWTBearer(context.TODO(), JWTBearerOptions{
+		RequestOptions: RequestOptions{TokenURL: server.URL, ClientID: "c", ClientSecret: "s"},
+		Assertion:      "prebuilt",
+	})
+	if err != nil {
+		t.Fatalf("send jwt bearer: %v", err)
+	}
+}
+
+func TestSendJWTBearerMissingAssertion(t *testing.T) {
+	_, err := SendJWTBearer(context.TODO(), JWTBearerOptions{
+		RequestOptions: RequestOptions{TokenURL: "http://localhost/token"},
+	})
+	if err == nil {
+		t.Errorf("expected error without assertion or key")
+	}
+}
+
+func TestSendJWTBearerFreshAssertionPerAttempt(t *testing.T) {
+	key := newTestKeys(t)["RS256"]
+
+	var jtis []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, err := DecodeRequestBody(r)
+		if err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		claims := jwt.MapClaims{}
+		if _, _, err := jwt.NewParser().ParseUnverified(req.Assertion, claims); err != nil {
+			t.Fatalf("parse assertion: %v", err)
+		}
+		jti, _ := claims["jti"].(string)
+		jtis = append(jtis, jti)
+		if len(jtis) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(EncodeResponseBody("at", "", 60)))
+	}))
+	defer server.Close()
+
+	_, err := SendJWTBearer(context.TODO(), JWTBearerOptions{
+		RequestOptions: RequestOptions{
+			TokenURL: server.URL,
+			Retry:    &RetryPolicy{BackoffBase: time.Millisecond},
+		},
+		AssertionKey: key,
+		Issuer:       "svc",
+	})
+	if err != nil {
+		t.Fatalf("send jwt bearer: %v", err)
+	}
+
+	if len(jtis) != 3 {
+		t.Fatalf("expected 3 attempts, got %d", len(jtis))
+	}
+	seen := map[string]bool{}
+	for _, jti := range jtis {
+		if jti == "" || seen[jti] {
+			t.Errorf("expected a distinct jti per attempt, got %v", jtis)
+			break
+		}
+		seen[jti] = true
+	}
+}
+
+func TestSendJWTBearerEndpointAudience(t *testing.T) {
+	key := newTestKeys(t)["RS256"]
+
+	var audiences []string
+
+	handler := func(status int) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			req, err := DecodeRequestBody(r)
+			if err != nil {
+				t.Fatalf("decode request: %v", err)
+			}
+			claims := jwt.MapClaims{}
+			if _, _, err := jwt.NewParser().ParseUnverified(req.Assertion, claims); err != nil {
+				t.Fatalf("parse assertion: %v", err)
+			}
+			aud, _ := claims["aud"].(string)
+			audiences = append(audiences, aud)
+			w.WriteHeader(status)
+			w.Write([]byte(EncodeResponseBody("at", "", 60)))
+		}
+	}
+
+	primary := httptest.NewServer(handler(http.StatusServiceUnavailable))
+	defer primary.Close()
+	secondary := httptest.NewServer(handler(http.StatusOK))
+	defer secondary.Close()
+
+	pool := NewEndpointPool(EndpointPoolOptions{
+		Endpoints: []Endpoint{{URL: primary.URL}, {URL: secondary.URL}},
+	})
+
+	_, err := SendJWTBearer(context.TODO(), JWTBearerOptions{
+		RequestOptions: RequestOptions{Endpoints: pool},
+		AssertionKey:   key,
+		Issuer:         "svc",
+	})
+	if err != nil {
+		t.Fatalf("send jwt bearer: %v", err)
+	}
+
+	want := []string{primary.URL, secondary.URL}
+	if len(audiences) != len(want) || audiences[0] != want[0] || audiences[1] != want[1] {
+		t.Errorf("expected audiences %v, got %v", want, audiences)
+	}
+}
@@ -0,0 +1,112 @@
+package clientcredentials
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newTestKeys(t *testing.T) map[string]crypto.Signer {
+	t.Helper()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("ecdsa key: %v", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("ed25519 key: %v", err)
+	}
+
+	return map[string]crypto.Signer{
+		"RS256": rsaKey,
+		"ES256": ecKey,
+		"EdDSA": edKey,
+	}
+}
+
+func TestPrivateKeyJWT(t *testing.T) {
+	const clientID = "myclientid"
+
+	for alg, key := range newTestKeys(t) {
+		t.Run(alg, func(t *testing.T) {
+			var tokenURL string
+			seen := map[string]bool{}
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				req, err := DecodeRequestBody(r)
+				if err != nil {
+					t.Fatalf("decode request: %v", err)
+				}
+				if req.ClientSecret != "" {
+					t.Errorf("unexpected client_secret")
+				}
+				if req.ClientAssertionType != ClientAssertionTypeJWTBearer {
+					t.Errorf("unexpected client_assertion_type: %s", req.ClientAssertionType)
+				}
+
+				token, err := jwt.Parse(req.ClientAssertion, func(token *jwt.Token) (any, error) {
+					if token.Header["kid"] != "kid1" {
+						t.Errorf("unexpected kid: %v", token.Header["kid"])
+					}
+					return key.Public(), nil
+				}, jwt.WithValidMethods([]string{alg}),
+					jwt.WithIssuer(clientID),
+					jwt.WithSubject(clientID),
+					jwt.WithAudience(tokenURL),
+					jwt.WithExpirationRequired())
+				if err != nil {
+					t.Fatalf("parse assertion: %v", err)
+				}
+
+				jti, _ := token.Claims.(jwt.MapClaims)["jti"].(string)
+				if jti == "" || seen[jti] {
+					t.Errorf("expected fresh jti, got '%s'", jti)
+				}
+				seen[jti] = true
+
+				w.Write([]byte(EncodeResponseBody("at", "", 60)))
+			}))
+			defer server.Close()
+
+			tokenURL = server.URL
+
+			options := RequestOptions{
+				TokenURL:   tokenURL,
+				ClientID:   clientID,
+				AuthMethod: AuthMethodPrivateKeyJWT,
+				PrivateKey: key,
+				KeyID:      "kid1",
+			}
+
+			for range 2 {
+				if _, err := SendRequest(context.TODO(), options); err != nil {
+					t.Fatalf("send request: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestPrivateKeyJWTMissingKey(t *testing.T) {
+	_, err := SendRequest(context.TODO(), RequestOptions{
+		TokenURL:   "http://localhost/token",
+		AuthMethod: AuthMethodPrivateKeyJWT,
+	})
+	if err == nil {
+		t.Errorf("expected error for missing private key")
+	}
+}
@@ -0,0 +1,127 @@
+package clientcredentials
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrCertificateBindingMismatch is returned by VerifyCertificateBinding when
+// the token is bound to a different certificate.
+var ErrCertificateBindingMismatch = errors.New("oauth2clientcredentials: token certificate binding mismatch")
+
+// NewMTLSHTTPClient creates an *http.Client that presents cert for mutual
+// TLS. If rootCAs is nil, the host root CA set is used to verify the server.
+func NewMTLSHTTPClient(cert tls.Certificate, rootCAs *x509.CertPool) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      rootCAs,
+		MinVersion:   tls.VersionTLS12,
+	}
+	return &http.Client{Transport: transport}
+}
+
+// maxMTLSClients bounds the cached mutual TLS clients, so that services
+// reloading their certificate on rotation do not leak transports.
+const maxMTLSClients = 16
+
+// mtlsClients caches one client per certificate chain, keyed by its
+// SHA-256 fingerprint, so that connections are reused across requests and
+// across reloads of the same certificate. The least recently used client
+// is evicted and its idle connections closed.
+var mtlsClients = struct {
+	sync.Mutex
+	lru     *list.List // of *mtlsEntry, most recently used first
+	entries map[[sha256.Size]byte]*list.Element
+}{
+	lru:     list.New(),
+	entries: map[[sha256.Size]byte]*list.Element{},
+}
+
+type mtlsEntry struct {
+	key    [sha256.Size]byte
+	client *http.Client
+}
+
+// mtlsClient returns the cached mutual TLS client for cert.
+func mtlsClient(cert *tls.Certificate) *http.Client {
+	h := sha256.New()
+	for _, der := range cert.Certificate {
+		h.Write(der)
+	}
+	var key [sha256.Size]byte
+	h.Sum(key[:0])
+
+	mtlsClients.Lock()
+	defer mtlsClients.Unlock()
+
+	if elem, found := mtlsClients.entries[key]; found {
+		mtlsClients.lru.MoveToFront(elem)
+		return elem.Value.(*mtlsEntry).client
+	}
+
+	client := NewMTLSHTTPClient(*cert, nil)
+	mtlsClients.entries[key] = mtlsClients.lru.PushFront(&mtlsEntry{key: key, client: client})
+
+	if mtlsClients.lru.Len() > maxMTLSClients {
+		oldest := mtlsClients.lru.Back()
+		mtlsClients.lru.Remove(oldest)
+		entry := oldest.Value.(*mtlsEntry)
+		delete(mtlsClients.entries, entry.key)
+		entry.client.CloseIdleConnections()
+	}
+
+	return client
+}
+
+// CertificateThumbprint returns the x5t#S256 thumbprint of cert: the
+// base64url-encoded SHA-256 hash of its DER encoding (RFC 8705 section 3.1).
+func CertificateThumbprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// TokenCertificateThumbprint extracts the cnf/x5t#S256 confirmation claim
+// from a JWT access token. The token signature is NOT verified; the
+// resource server is responsible for validating the token itself.
+func TokenCertificateThumbprint(accessToken string) (string, error) {
+	var claims struct {
+		jwt.RegisteredClaims
+		Cnf struct {
+			X5tS256 string `json:"x5t#S256"`
+		} `json:"cnf"`
+	}
+
+	if _, _, err := jwt.NewParser().ParseUnverified(accessToken, &claims); err != nil {
+		return "", fmt.Errorf("oauth2clientcredentials: parse access token: %w", err)
+	}
+
+	if claims.Cnf.X5tS256 == "" {
+		return "", fmt.Errorf("oauth2clientcredentials: access token has no cnf/x5t#S256 claim")
+	}
+
+	return claims.Cnf.X5tS256, nil
+}
+
+// VerifyCertificateBinding checks that the JWT access token is bound to
+// cert through its cnf/x5t#S256 claim.
+func VerifyCertificateBinding(accessToken string, cert *x509.Certificate) error {
+	thumbprint, err := TokenCertificateThumbprint(accessToken)
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare([]byte(thumbprint), []byte(CertificateThumbprint(cert))) != 1 {
+		return ErrCertificateBindingMismatch
+	}
+	return nil
+}
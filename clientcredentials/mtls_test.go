@@ -0,0 +1,156 @@
+package clientcredentials
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newSelfSignedCertificate(t *testing.T, commonName string) tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatalf("certificate: %v", err)
+	}
+
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse certificate: %v", err)
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func TestMTLS(t *testing.T) {
+	const clientID = "myclientid"
+
+	clientCert := newSelfSignedCertificate(t, clientID)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, err := DecodeRequestBody(r)
+		if err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		if req.ClientID != clientID {
+			t.Errorf("expected client_id %s, got %s", clientID, req.ClientID)
+		}
+		if req.ClientSecret != "" {
+			t.Errorf("unexpected client_secret")
+		}
+		if len(r.TLS.PeerCertificates) == 0 {
+			t.Fatalf("missing client certificate")
+		}
+
+		// issue certificate-bound token
+		claims := jwt.MapClaims{
+			"sub": req.ClientID,
+			"cnf": map[string]any{"x5t#S256": CertificateThumbprint(r.TLS.PeerCertificates[0])},
+		}
+		accessToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("secret"))
+		if err != nil {
+			t.Fatalf("sign: %v", err)
+		}
+
+		w.Write([]byte(EncodeResponseBody(accessToken, "", 60)))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+
+	resp, err := SendRequest(context.TODO(), RequestOptions{
+		HTTPClient: NewMTLSHTTPClient(clientCert, roots),
+		TokenURL:   server.URL,
+		ClientID:   clientID,
+		AuthMethod: AuthMethodSelfSignedTLSClientAuth,
+	})
+	if err != nil {
+		t.Fatalf("send request: %v", err)
+	}
+
+	if err := VerifyCertificateBinding(resp.AccessToken, clientCert.Leaf); err != nil {
+		t.Errorf("verify binding: %v", err)
+	}
+
+	other := newSelfSignedCertificate(t, "other")
+	if err := VerifyCertificateBinding(resp.AccessToken, other.Leaf); !errors.Is(err, ErrCertificateBindingMismatch) {
+		t.Errorf("expected ErrCertificateBindingMismatch, got %v", err)
+	}
+}
+
+func TestMTLSRequiresCertificate(t *testing.T) {
+	_, err := SendRequest(context.TODO(), RequestOptions{
+		TokenURL:   "https://localhost/token",
+		AuthMethod: AuthMethodTLSClientAuth,
+	})
+	if err == nil {
+		t.Errorf("expected error for missing client certificate")
+	}
+}
+
+func TestMTLSClientCache(t *testing.T) {
+	cert := newSelfSignedCertificate(t, "c")
+	if mtlsClient(&cert) != mtlsClient(&cert) {
+		t.Errorf("expected cached client for the same certificate")
+	}
+
+	// a reloaded copy of the same certificate reuses the client
+	reloaded := cert
+	if mtlsClient(&cert) != mtlsClient(&reloaded) {
+		t.Errorf("expected cached client for a reloaded certificate")
+	}
+
+	// rotations do not grow the cache without bound
+	for i := range maxMTLSClients + 4 {
+		rotated := newSelfSignedCertificate(t, "rotated"+strconv.Itoa(i))
+		mtlsClient(&rotated)
+	}
+	mtlsClients.Lock()
+	size := mtlsClients.lru.Len()
+	mtlsClients.Unlock()
+	if size > maxMTLSClients {
+		t.Errorf("expected at most %d cached clients, got %d", maxMTLSClients, size)
+	}
+}
+
+func TestTokenCertificateThumbprintMissing(t *testing.T) {
+	accessToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "x"}).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	if _, err := TokenCertificateThumbprint(accessToken); err == nil {
+		t.Errorf("expected error for token without cnf")
+	}
+	if _, err := TokenCertificateThumbprint("not-a-jwt"); err == nil {
+		t.Errorf("expected error for opaque token")
+	}
+}
@@ -2,7 +2,6 @@ package clientcredentials
 
 import (
 	"fmt"
-	"strconv"
 
 	"github.com/sugawarayuuta/sonnet"
 )
@@ -42,17 +41,39 @@ func parseToken(buf []byte, debugf func(format string, v ...any)) (Response, err
 
 	info.AccessToken = tokenStr
 
+	for _, field := range []struct {
+		key   string
+		value *string
+	}{
+		{"scope", &info.Scope},
+		{"issued_token_type", &info.IssuedTokenType},
+		{"refresh_token", &info.RefreshToken},
+		{"id_token", &info.IDToken},
+	} {
+		str, errStr := optionalString(data, field.key)
+		if errStr != nil {
+			return info, errStr
+		}
+		*field.value = str
+	}
+
 	expire, foundExpire := data["expires_in"]
 	if foundExpire {
 		switch expireVal := expire.(type) {
+		case nil:
+			debugf("found null expires_in field")
 		case float64:
 			debugf("found expires_in field with %f seconds", expireVal)
-			info.ExpiresIn = int(expireVal)
+			exp, errConv := expiresInFromFloat(expireVal)
+			if errConv != nil {
+				return info, errConv
+			}
+			info.ExpiresIn = exp
 		case string:
 			debugf("found expires_in field with %s seconds", expireVal)
-			exp, errConv := strconv.Atoi(expireVal)
+			exp, errConv := expiresInFromString(expireVal)
 			if errConv != nil {
-				return info, fmt.Errorf("error converting expires_in field from string='%s' to int: %v", expireVal, errConv)
+				return info, errConv
 			}
 			info.ExpiresIn = exp
 		default:
@@ -62,3 +83,16 @@ func parseToken(buf []byte, debugf func(format string, v ...any)) (Response, err
 
 	return info, nil
 }
+
+// optionalString returns the string field key, or empty if it is missing.
+func optionalString(data map[string]any, key string) (string, error) {
+	value, found := data[key]
+	if !found {
+		return "", nil
+	}
+	str, isStr := value.(string)
+	if !isStr {
+		return "", fmt.Errorf("non-string value for %s field in token response", key)
+	}
+	return str, nil
+}
@@ -37,6 +37,8 @@ var parseTokenTestTable = []parseTokenTestCase{
 	{"expire broken string", `{"access_token":"abc","expires_in":"TTT"}`, expectFailure, "", "", 0},
 	{"expire empty string", `{"access_token":"abc","expires_in":""}`, expectFailure, "", "", 0},
 	{"expire broken bool", `{"access_token":"abc","expires_in":true}`, expectFailure, "", "", 0},
+	{"expire huge integer", `{"access_token":"abc","expires_in":99999999999}`, expectFailure, "", "", 0},
+	{"expire huge string", `{"access_token":"abc","expires_in":"99999999999"}`, expectFailure, "", "", 0},
 }
 
 func TestParseToken(t *testing.T) {
@@ -0,0 +1,64 @@
+package clientcredentials
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// DefaultMaxResponseSize is the default limit for token endpoint responses.
+const DefaultMaxResponseSize = 1 << 20 // 1 MiB
+
+// ErrResponseTooLarge is returned when a response body exceeds the
+// configured maximum size. Use errors.Is to detect it.
+var ErrResponseTooLarge = errors.New("oauth2clientcredentials: response body too large")
+
+// maxPooledBufferSize keeps occasional large responses from pinning
+// memory in the pool.
+const maxPooledBufferSize = 64 << 10
+
+var bufferPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// readBody reads at most limit bytes from r into a pooled buffer.
+// The caller must release the buffer with putBuffer once done with it;
+// the buffer bytes must not be retained after that.
+func readBody(r io.Reader, contentLength, limit int64) (*bytes.Buffer, error) {
+	if contentLength > limit {
+		return nil, fmt.Errorf("%w: content length %d exceeds limit %d", ErrResponseTooLarge, contentLength, limit)
+	}
+
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+
+	if _, err := buf.ReadFrom(io.LimitReader(r, limit+1)); err != nil {
+		putBuffer(buf)
+		return nil, err
+	}
+
+	if int64(buf.Len()) > limit {
+		putBuffer(buf)
+		return nil, fmt.Errorf("%w: exceeds limit %d", ErrResponseTooLarge, limit)
+	}
+
+	return buf, nil
+}
+
+// readErrorBody reads at most limit bytes of an error response body.
+// Longer bodies, like a proxy's HTML error page, are truncated instead of
+// rejected, so that the status code is still reported. Read errors are
+// ignored for the same reason.
+func readErrorBody(r io.Reader, limit int64) []byte {
+	body, _ := io.ReadAll(io.LimitReader(r, limit))
+	return body
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	bufferPool.Put(buf)
+}
@@ -0,0 +1,113 @@
+package clientcredentials
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSendRequestMaxResponseSize(t *testing.T) {
+	body := `{"access_token":"` + strings.Repeat("a", 1000) + `","token_type":"Bearer"}`
+
+	tests := []struct {
+		name    string
+		limit   int64
+		chunked bool
+		wantErr bool
+	}{
+		{"default limit", 0, false, false},
+		{"within limit", int64(len(body)), false, false},
+		{"content length over limit", 100, false, true},
+		{"chunked over limit", 100, true, true},
+		{"chunked within limit", int64(len(body)), true, false},
+	}
+
+	for _, tt := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if tt.chunked {
+				w.(http.Flusher).Flush()
+			}
+			w.Write([]byte(body))
+		}))
+
+		resp, err := SendRequest(context.TODO(), RequestOptions{
+			TokenURL:        server.URL,
+			ClientID:        "id",
+			MaxResponseSize: tt.limit,
+		})
+		server.Close()
+
+		if tt.wantErr {
+			if !errors.Is(err, ErrResponseTooLarge) {
+				t.Errorf("%s: expected ErrResponseTooLarge, got %v", tt.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if len(resp.AccessToken) != 1000 {
+			t.Errorf("%s: unexpected access_token length: %d", tt.name, len(resp.AccessToken))
+		}
+	}
+}
+
+func TestSendRequestLargeErrorBody(t *testing.T) {
+	page := "<html>" + strings.Repeat("bad gateway ", 6000) + "</html>" // about 70 KiB
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte(page))
+	}))
+	defer server.Close()
+
+	_, err := SendRequest(context.TODO(), RequestOptions{
+		TokenURL:        server.URL,
+		MaxResponseSize: 64 << 10,
+	})
+
+	var errResp *ErrorResponse
+	if !errors.As(err, &errResp) {
+		t.Fatalf("expected ErrorResponse, got %v", err)
+	}
+	if errResp.StatusCode != http.StatusBadGateway {
+		t.Errorf("expected status 502, got %d", errResp.StatusCode)
+	}
+	if errResp.Header.Get("Content-Type") != "text/html" {
+		t.Errorf("expected header preserved, got %v", errResp.Header)
+	}
+	if len(errResp.Body) != 64<<10 {
+		t.Errorf("expected body truncated to the limit, got %d bytes", len(errResp.Body))
+	}
+}
+
+func TestSendRequestErrorBodyNotShared(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		if r.URL.Path == "/first" {
+			w.Write([]byte(`{"error":"invalid_scope"}`))
+			return
+		}
+		w.Write([]byte(`{"error":"invalid_grant"}`))
+	}))
+	defer server.Close()
+
+	_, errFirst := SendRequest(context.TODO(), RequestOptions{TokenURL: server.URL + "/first"})
+	_, errSecond := SendRequest(context.TODO(), RequestOptions{TokenURL: server.URL + "/second"})
+
+	var first, second *ErrorResponse
+	if !errors.As(errFirst, &first) || !errors.As(errSecond, &second) {
+		t.Fatalf("expected ErrorResponse errors, got %v and %v", errFirst, errSecond)
+	}
+	if string(first.Body) != `{"error":"invalid_scope"}` {
+		t.Errorf("first body was overwritten: %s", first.Body)
+	}
+	if second.Code != ErrorCodeInvalidGrant {
+		t.Errorf("unexpected second code: %s", second.Code)
+	}
+}
@@ -0,0 +1,254 @@
+package clientcredentials
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// Defaults for RefresherOptions.
+const (
+	DefaultMinRefreshRatio  = 0.75
+	DefaultMaxRefreshRatio  = 0.90
+	DefaultRetryInterval    = 5 * time.Second
+	DefaultNoExpiryInterval = 5 * time.Minute
+)
+
+// ErrRefresherNotStarted is returned by Refresher.Token when the refresher
+// has not been started.
+var ErrRefresherNotStarted = errors.New("oauth2clientcredentials: refresher not started")
+
+// ErrTokenExpired is returned by Refresher.Token when the current token
+// has expired and no refresh has completed since, for example while a slow
+// refresh is still in flight.
+var ErrTokenExpired = errors.New("oauth2clientcredentials: token expired")
+
+// RefresherOptions contains options for creating a Refresher.
+type RefresherOptions struct {
+	// RequestOptions is used to fetch tokens from the token endpoint.
+	RequestOptions RequestOptions
+
+	// Fetch is optional function used to fetch tokens.
+	// If nil, SendRequest will be used.
+	Fetch FetchFunc
+
+	// MinRefreshRatio and MaxRefreshRatio define the fraction of expires_in
+	// after which the token is refreshed. The actual point is picked at random
+	// in [MinRefreshRatio, MaxRefreshRatio) for every token, so that replicas
+	// do not refresh in lockstep.
+	// If zero, DefaultMinRefreshRatio and DefaultMaxRefreshRatio will be used.
+	MinRefreshRatio float64
+	MaxRefreshRatio float64
+
+	// RetryInterval is optional interval between attempts after a failed refresh.
+	// If zero, DefaultRetryInterval will be used.
+	RetryInterval time.Duration
+
+	// NoExpiryInterval is optional refresh interval for tokens without expires_in.
+	// If zero, DefaultNoExpiryInterval will be used.
+	NoExpiryInterval time.Duration
+
+	// OnRefresh is optional callback invoked after every successful refresh.
+	OnRefresh func(resp Response)
+
+	// OnError is optional callback invoked after every failed refresh.
+	OnError func(err error)
+}
+
+// Refresher keeps a token refreshed in a background goroutine, so that
+// callers of Token never block on the token endpoint once the first token
+// has been obtained.
+// Refresher is safe for concurrent use by multiple goroutines.
+type Refresher struct {
+	options RefresherOptions
+
+	mutex   sync.Mutex
+	token   Response
+	expiry  time.Time // zero means no known expiry
+	valid   bool
+	lastErr error
+	ready   chan struct{} // closed after the first refresh attempt
+	cancel  context.CancelFunc
+	done    chan struct{} // closed when the background goroutine exits
+
+	now func() time.Time
+}
+
+// NewRefresher creates a Refresher. Call Start to begin refreshing.
+func NewRefresher(options RefresherOptions) *Refresher {
+	if options.Fetch == nil {
+		options.Fetch = SendRequest
+	}
+	if options.MinRefreshRatio == 0 {
+		options.MinRefreshRatio = DefaultMinRefreshRatio
+	}
+	if options.MaxRefreshRatio == 0 {
+		options.MaxRefreshRatio = DefaultMaxRefreshRatio
+	}
+	if options.MaxRefreshRatio < options.MinRefreshRatio {
+		options.MaxRefreshRatio = options.MinRefreshRatio
+	}
+	if options.RetryInterval == 0 {
+		options.RetryInterval = DefaultRetryInterval
+	}
+	if options.NoExpiryInterval == 0 {
+		options.NoExpiryInterval = DefaultNoExpiryInterval
+	}
+	return &Refresher{
+		options: options,
+		ready:   make(chan struct{}),
+		now:     time.Now,
+	}
+}
+
+// Start fetches the first token and keeps refreshing it in a background
+// goroutine until ctx is done or Stop is called. Either way the refresher
+// is stopped afterwards and can be started again.
+// Calling Start on a running refresher has no effect.
+func (r *Refresher) Start(ctx context.Context) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.cancel != nil {
+		return
+	}
+
+	ctx, r.cancel = context.WithCancel(ctx)
+	r.done = make(chan struct{})
+
+	go r.run(ctx, r.done)
+}
+
+// Stop stops the background goroutine and waits for it to exit.
+// The refresher can be started again afterwards.
+func (r *Refresher) Stop() {
+	r.mutex.Lock()
+	cancel, done := r.cancel, r.done
+	r.cancel, r.done = nil, nil
+	r.mutex.Unlock()
+
+	if cancel == nil {
+		return
+	}
+
+	cancel()
+	<-done
+}
+
+// Token returns the current token. Before the first refresh attempt has
+// completed, it waits for it, for the refresher to stop or for ctx to be
+// done. Without a valid token, Token returns ErrRefresherNotStarted when
+// the refresher is stopped, the error of the last refresh if it failed, or
+// ErrTokenExpired while a refresh is in flight. It never returns an empty
+// token without error.
+func (r *Refresher) Token(ctx context.Context) (Response, error) {
+	r.mutex.Lock()
+	done := r.done
+	r.mutex.Unlock()
+
+	select {
+	case <-r.ready:
+	default:
+		if done == nil {
+			return Response{}, ErrRefresherNotStarted
+		}
+		select {
+		case <-r.ready:
+		case <-done: // stopped before the first refresh, reported below
+		case <-ctx.Done():
+			return Response{}, ctx.Err()
+		}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.valid && (r.expiry.IsZero() || r.now().Before(r.expiry)) {
+		return r.token, nil
+	}
+
+	switch {
+	case r.cancel == nil:
+		return Response{}, ErrRefresherNotStarted
+	case r.lastErr != nil:
+		return Response{}, r.lastErr
+	}
+
+	return Response{}, ErrTokenExpired
+}
+
+func (r *Refresher) run(ctx context.Context, done chan struct{}) {
+	defer func() {
+		r.mutex.Lock()
+		if r.done == done {
+			// ctx was canceled by the parent, not by Stop
+			r.cancel()
+			r.cancel, r.done = nil, nil
+		}
+		r.mutex.Unlock()
+		close(done)
+	}()
+
+	for {
+		wait := r.refresh(ctx)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// refresh fetches a token and returns how long to wait before the next refresh.
+func (r *Refresher) refresh(ctx context.Context) time.Duration {
+	resp, err := r.options.Fetch(ctx, r.options.RequestOptions)
+	now := r.now()
+
+	r.mutex.Lock()
+	if err == nil {
+		r.token = resp
+		r.valid = true
+		r.lastErr = nil
+		r.expiry = expiryTime(now, resp.ExpiresIn, 0)
+	} else if ctx.Err() == nil {
+		r.lastErr = err
+	}
+	select {
+	case <-r.ready:
+	default:
+		if ctx.Err() == nil {
+			close(r.ready)
+		}
+	}
+	r.mutex.Unlock()
+
+	if err != nil {
+		if ctx.Err() == nil && r.options.OnError != nil {
+			r.options.OnError(err)
+		}
+		return r.options.RetryInterval
+	}
+
+	if r.options.OnRefresh != nil {
+		r.options.OnRefresh(resp)
+	}
+
+	return r.refreshDelay(resp.ExpiresIn)
+}
+
+// refreshDelay picks a random point in [MinRefreshRatio, MaxRefreshRatio)
+// of the token lifetime.
+func (r *Refresher) refreshDelay(expiresIn int) time.Duration {
+	if expiresIn <= 0 {
+		return r.options.NoExpiryInterval
+	}
+	lifetime := float64(time.Duration(expiresIn) * time.Second)
+	ratio := r.options.MinRefreshRatio +
+		rand.Float64()*(r.options.MaxRefreshRatio-r.options.MinRefreshRatio)
+	return time.Duration(lifetime * ratio)
+}
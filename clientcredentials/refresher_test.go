@@ -0,0 +1,208 @@
+package clientcredentials
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRefresher(t *testing.T) {
+	var count, refreshed int32
+
+	r := NewRefresher(RefresherOptions{
+		Fetch: func(_ context.Context, _ RequestOptions) (Response, error) {
+			n := atomic.AddInt32(&count, 1)
+			return Response{AccessToken: "token" + strconv.Itoa(int(n)), ExpiresIn: 1}, nil
+		},
+		MinRefreshRatio: 0.01,
+		MaxRefreshRatio: 0.02,
+		OnRefresh:       func(_ Response) { atomic.AddInt32(&refreshed, 1) },
+	})
+
+	if _, err := r.Token(context.TODO()); !errors.Is(err, ErrRefresherNotStarted) {
+		t.Fatalf("expected ErrRefresherNotStarted, got %v", err)
+	}
+
+	r.Start(context.TODO())
+
+	tok, err := r.Token(context.TODO())
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+	if tok.AccessToken == "" {
+		t.Errorf("expected access token")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt32(&refreshed) < 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	r.Stop()
+
+	stopped := atomic.LoadInt32(&count)
+	if stopped < 3 {
+		t.Errorf("expected at least 3 refreshes, got %d", stopped)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if got := atomic.LoadInt32(&count); got != stopped {
+		t.Errorf("refresher kept running after Stop: %d != %d", got, stopped)
+	}
+
+	// token remains available after stop while not expired
+	if _, err := r.Token(context.TODO()); err != nil {
+		t.Errorf("token after stop: %v", err)
+	}
+}
+
+func TestRefresherError(t *testing.T) {
+	errFetch := errors.New("fetch failed")
+	errCh := make(chan error, 10)
+
+	r := NewRefresher(RefresherOptions{
+		Fetch: func(_ context.Context, _ RequestOptions) (Response, error) {
+			return Response{}, errFetch
+		},
+		RetryInterval: time.Hour,
+		OnError: func(err error) {
+			errCh <- err
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+
+	r.Start(ctx)
+	defer r.Stop()
+
+	if _, err := r.Token(context.TODO()); !errors.Is(err, errFetch) {
+		t.Errorf("expected fetch error from Token, got %v", err)
+	}
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, errFetch) {
+			t.Errorf("expected fetch error in callback, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("OnError not called")
+	}
+}
+
+func TestRefreshDelay(t *testing.T) {
+	r := NewRefresher(RefresherOptions{})
+
+	for range 100 {
+		d := r.refreshDelay(100)
+		if d < 75*time.Second || d >= 90*time.Second {
+			t.Fatalf("refresh delay out of range: %v", d)
+		}
+	}
+
+	if d := r.refreshDelay(0); d != DefaultNoExpiryInterval {
+		t.Errorf("expected %v for missing expires_in, got %v", DefaultNoExpiryInterval, d)
+	}
+}
+
+func TestRefresherExpiredToken(t *testing.T) {
+	var count int32
+	release := make(chan struct{})
+
+	r := NewRefresher(RefresherOptions{
+		Fetch: func(ctx context.Context, _ RequestOptions) (Response, error) {
+			if atomic.AddInt32(&count, 1) == 1 {
+				return Response{AccessToken: "token1", ExpiresIn: 60}, nil
+			}
+			// slow refresh
+			select {
+			case <-release:
+				return Response{AccessToken: "token2", ExpiresIn: 60}, nil
+			case <-ctx.Done():
+				return Response{}, ctx.Err()
+			}
+		},
+		MinRefreshRatio: 0.0001,
+		MaxRefreshRatio: 0.0001,
+	})
+	defer close(release)
+
+	now := time.Now()
+	var offset atomic.Int64
+	r.now = func() time.Time { return now.Add(time.Duration(offset.Load())) }
+
+	r.Start(context.TODO())
+
+	if tok, err := r.Token(context.TODO()); err != nil || tok.AccessToken != "token1" {
+		t.Fatalf("expected token1, got '%s' %v", tok.AccessToken, err)
+	}
+
+	for atomic.LoadInt32(&count) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// expired while the refresh is in flight
+	offset.Store(int64(time.Minute))
+	if tok, err := r.Token(context.TODO()); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("expected ErrTokenExpired, got '%s' %v", tok.AccessToken, err)
+	}
+
+	// expired after stop
+	r.Stop()
+	if tok, err := r.Token(context.TODO()); !errors.Is(err, ErrRefresherNotStarted) {
+		t.Errorf("expected ErrRefresherNotStarted after stop, got '%s' %v", tok.AccessToken, err)
+	}
+}
+
+func TestRefresherParentCanceled(t *testing.T) {
+	var failing atomic.Bool
+	failing.Store(true)
+
+	r := NewRefresher(RefresherOptions{
+		Fetch: func(ctx context.Context, _ RequestOptions) (Response, error) {
+			if failing.Load() {
+				<-ctx.Done() // first refresh never completes
+				return Response{}, ctx.Err()
+			}
+			return Response{AccessToken: "at", ExpiresIn: 60}, nil
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	r.Start(ctx)
+
+	waiting := make(chan error)
+	go func() {
+		_, err := r.Token(context.TODO())
+		waiting <- err
+	}()
+
+	cancel()
+
+	select {
+	case err := <-waiting:
+		if !errors.Is(err, ErrRefresherNotStarted) {
+			t.Errorf("expected ErrRefresherNotStarted for waiting caller, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("waiting caller not released after parent cancel")
+	}
+
+	if _, err := r.Token(context.TODO()); !errors.Is(err, ErrRefresherNotStarted) {
+		t.Errorf("expected ErrRefresherNotStarted after parent cancel, got %v", err)
+	}
+
+	// can be started again
+	failing.Store(false)
+	r.Start(context.TODO())
+	defer r.Stop()
+
+	tokenCtx, tokenCancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer tokenCancel()
+	if tok, err := r.Token(tokenCtx); err != nil || tok.AccessToken != "at" {
+		t.Errorf("expected token after restart, got '%s' %v", tok.AccessToken, err)
+	}
+}
@@ -0,0 +1,123 @@
+package clientcredentials
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// RequestGroup collapses concurrent token requests for the same
+// TokenURL, ClientID, Scope, Audience, Resource, ExtraParams,
+// ExtraHeaders and DPoP key into a single in-flight request.
+// All concurrent callers receive the response or error of the shared request.
+// The zero value is ready to use and RequestGroup is safe for concurrent use.
+type RequestGroup struct {
+	// Fetch is optional function used to fetch tokens.
+	// If nil, SendRequest will be used.
+	Fetch FetchFunc
+
+	mutex sync.Mutex
+	calls map[string]*groupCall
+}
+
+type groupCall struct {
+	done chan struct{}
+	resp Response
+	err  error
+}
+
+// SendRequest sends a client credentials token request, sharing the
+// request with any concurrent caller that uses the same key.
+//
+// The shared request is not canceled when the caller that started it gives
+// up: it runs detached from cancellation, bounded by that caller's
+// deadline, if any. Every caller, including the first one, stops waiting
+// when its own context is done. A panic in Fetch is reported to all
+// callers as an error.
+func (g *RequestGroup) SendRequest(ctx context.Context, options RequestOptions) (Response, error) {
+	key := requestKey(options)
+
+	g.mutex.Lock()
+	if g.calls == nil {
+		g.calls = map[string]*groupCall{}
+	}
+	c, found := g.calls[key]
+	if !found {
+		c = &groupCall{done: make(chan struct{})}
+		g.calls[key] = c
+		go g.fetch(ctx, key, c, options)
+	}
+	g.mutex.Unlock()
+
+	select {
+	case <-c.done:
+		return c.resp, c.err
+	case <-ctx.Done():
+		return Response{}, ctx.Err()
+	}
+}
+
+// fetch runs the shared request for key and releases the waiters.
+func (g *RequestGroup) fetch(ctx context.Context, key string, c *groupCall, options RequestOptions) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.resp, c.err = Response{}, fmt.Errorf("oauth2clientcredentials.RequestGroup: fetch panic: %v", r)
+		}
+		g.mutex.Lock()
+		delete(g.calls, key)
+		g.mutex.Unlock()
+		close(c.done)
+	}()
+
+	fetchCtx, cancel := detachContext(ctx)
+	defer cancel()
+
+	fetch := g.Fetch
+	if fetch == nil {
+		fetch = SendRequest
+	}
+
+	c.resp, c.err = fetch(fetchCtx, options)
+}
+
+// detachContext returns a context that is not canceled with ctx, but
+// keeps its values and deadline, for fetches shared with other callers.
+func detachContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	detached := context.WithoutCancel(ctx)
+	if deadline, ok := ctx.Deadline(); ok {
+		return context.WithDeadline(detached, deadline)
+	}
+	return detached, func() {}
+}
+
+// requestKey identifies requests that can share a single token.
+func requestKey(options RequestOptions) string {
+	var jkt string
+	if options.DPoP != nil {
+		jkt = options.DPoP.Thumbprint() // tokens are bound to the key
+	}
+	fields := append([]string{tokenURLKey(options), options.ClientID, options.Scope, options.Audience,
+		jkt, extraKey(options)}, options.Resource...)
+	return strings.Join(fields, "\x00")
+}
+
+// extraKey canonically encodes ExtraParams and ExtraHeaders, which may
+// select a tenant, for request keys.
+func extraKey(options RequestOptions) string {
+	if len(options.ExtraParams) == 0 && len(options.ExtraHeaders) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	appendExtra(&b, options.ExtraParams)
+	headers := url.Values{}
+	for k, v := range options.ExtraHeaders {
+		k = http.CanonicalHeaderKey(k)
+		headers[k] = append(headers[k], v...)
+	}
+	b.WriteByte(' ')
+	b.WriteString(headers.Encode())
+	return b.String()
+}
@@ -0,0 +1,254 @@
+package clientcredentials
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// startGroupCallers starts one caller, waits for its fetch to be in flight,
+// then starts the remaining callers and gives them time to join it.
+func startGroupCallers(g *RequestGroup, count *int32, callers int, options RequestOptions, results chan<- error) *sync.WaitGroup {
+	var wg sync.WaitGroup
+	call := func() {
+		resp, err := g.SendRequest(context.TODO(), options)
+		if err == nil && resp.AccessToken != options.ClientID {
+			err = errors.New("unexpected token: " + resp.AccessToken)
+		}
+		results <- err
+	}
+	wg.Go(call)
+	for atomic.LoadInt32(count) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	for range callers - 1 {
+		wg.Go(call)
+	}
+	time.Sleep(50 * time.Millisecond)
+	return &wg
+}
+
+func TestRequestGroup(t *testing.T) {
+	var count int32
+	release := make(chan struct{})
+
+	g := &RequestGroup{
+		Fetch: func(_ context.Context, options RequestOptions) (Response, error) {
+			atomic.AddInt32(&count, 1)
+			<-release
+			return Response{AccessToken: options.ClientID}, nil
+		},
+	}
+
+	const callers = 200
+	results := make(chan error, callers)
+
+	wg := startGroupCallers(g, &count, callers, RequestOptions{TokenURL: "u", ClientID: "c"}, results)
+	close(release)
+	wg.Wait()
+	close(results)
+
+	for err := range results {
+		if err != nil {
+			t.Errorf("send: %v", err)
+		}
+	}
+
+	if got := atomic.LoadInt32(&count); got != 1 {
+		t.Errorf("expected 1 request, got %d", got)
+	}
+
+	// distinct keys are not collapsed
+	atomic.StoreInt32(&count, 0)
+	g.SendRequest(context.TODO(), RequestOptions{TokenURL: "u", ClientID: "a"})
+	g.SendRequest(context.TODO(), RequestOptions{TokenURL: "u", ClientID: "b"})
+	if got := atomic.LoadInt32(&count); got != 2 {
+		t.Errorf("expected 2 requests for distinct keys, got %d", got)
+	}
+}
+
+func TestRequestKeyExtra(t *testing.T) {
+	base := RequestOptions{TokenURL: "u", ClientID: "c"}
+
+	tenant := func(param, header string) RequestOptions {
+		options := base
+		if param != "" {
+			options.ExtraParams = url.Values{"tenant": {param}}
+		}
+		if header != "" {
+			options.ExtraHeaders = http.Header{"X-Tenant": {header}}
+		}
+		return options
+	}
+
+	distinct := []RequestOptions{
+		base,
+		tenant("a", ""),
+		tenant("b", ""),
+		tenant("", "a"),
+		tenant("", "b"),
+	}
+	keys := map[string]int{}
+	for i, options := range distinct {
+		key := requestKey(options)
+		if j, found := keys[key]; found {
+			t.Errorf("options %d and %d share key %q", j, i, key)
+		}
+		keys[key] = i
+	}
+
+	// tokens bound to distinct DPoP keys are not shared
+	dpopA, errA := NewDPoP()
+	dpopB, errB := NewDPoP()
+	if errA != nil || errB != nil {
+		t.Fatalf("new dpop: %v %v", errA, errB)
+	}
+	withA, withB := base, base
+	withA.DPoP, withB.DPoP = dpopA, dpopB
+	if requestKey(withA) == requestKey(withB) || requestKey(withA) == requestKey(base) {
+		t.Errorf("expected distinct keys for distinct DPoP keys")
+	}
+
+	// header name case and parameter order do not matter
+	a := RequestOptions{ExtraParams: url.Values{"x": {"1"}, "y": {"2"}}, ExtraHeaders: http.Header{"x-tenant": {"a"}}}
+	b := RequestOptions{ExtraParams: url.Values{"y": {"2"}, "x": {"1"}}, ExtraHeaders: http.Header{"X-Tenant": {"a"}}}
+	if requestKey(a) != requestKey(b) {
+		t.Errorf("expected equivalent options to share key: %q %q", requestKey(a), requestKey(b))
+	}
+}
+
+func TestRequestGroupSharedError(t *testing.T) {
+	errFetch := errors.New("fetch failed")
+	release := make(chan struct{})
+	var count int32
+
+	g := &RequestGroup{
+		Fetch: func(_ context.Context, _ RequestOptions) (Response, error) {
+			atomic.AddInt32(&count, 1)
+			<-release
+			return Response{}, errFetch
+		},
+	}
+
+	results := make(chan error, 2)
+	wg := startGroupCallers(g, &count, 2, RequestOptions{TokenURL: "u"}, results)
+	close(release)
+	wg.Wait()
+	close(results)
+
+	for err := range results {
+		if !errors.Is(err, errFetch) {
+			t.Errorf("expected shared error, got %v", err)
+		}
+	}
+	if got := atomic.LoadInt32(&count); got != 1 {
+		t.Errorf("expected 1 request, got %d", got)
+	}
+}
+
+func TestRequestGroupCallerContext(t *testing.T) {
+	var count int32
+	release := make(chan struct{})
+	defer close(release)
+
+	g := &RequestGroup{
+		Fetch: func(_ context.Context, _ RequestOptions) (Response, error) {
+			atomic.AddInt32(&count, 1)
+			<-release
+			return Response{}, nil
+		},
+	}
+
+	go g.SendRequest(context.TODO(), RequestOptions{TokenURL: "u"})
+	for atomic.LoadInt32(&count) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+
+	if _, err := g.SendRequest(ctx, RequestOptions{TokenURL: "u"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled for waiting caller, got %v", err)
+	}
+}
+
+func TestRequestGroupPanic(t *testing.T) {
+	var count int32
+	release := make(chan struct{})
+
+	g := &RequestGroup{
+		Fetch: func(_ context.Context, _ RequestOptions) (Response, error) {
+			atomic.AddInt32(&count, 1)
+			<-release
+			panic("boom")
+		},
+	}
+
+	results := make(chan error, 3)
+	wg := startGroupCallers(g, &count, 3, RequestOptions{TokenURL: "u"}, results)
+	close(release)
+	wg.Wait()
+	close(results)
+
+	for err := range results {
+		if err == nil {
+			t.Errorf("expected error from panicking fetch")
+		}
+	}
+
+	// the key is released
+	g.Fetch = func(_ context.Context, options RequestOptions) (Response, error) {
+		return Response{AccessToken: "at"}, nil
+	}
+	if resp, err := g.SendRequest(context.TODO(), RequestOptions{TokenURL: "u"}); err != nil || resp.AccessToken != "at" {
+		t.Errorf("expected token after panic, got '%s' %v", resp.AccessToken, err)
+	}
+}
+
+func TestRequestGroupLeaderCanceled(t *testing.T) {
+	var count int32
+	release := make(chan struct{})
+
+	g := &RequestGroup{
+		Fetch: func(ctx context.Context, _ RequestOptions) (Response, error) {
+			atomic.AddInt32(&count, 1)
+			<-release
+			if err := ctx.Err(); err != nil {
+				return Response{}, err
+			}
+			return Response{AccessToken: "at"}, nil
+		},
+	}
+
+	leaderCtx, cancel := context.WithCancel(context.TODO())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := g.SendRequest(leaderCtx, RequestOptions{TokenURL: "u"})
+		leaderErr <- err
+	}()
+	for atomic.LoadInt32(&count) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	follower := make(chan Response, 1)
+	go func() {
+		resp, _ := g.SendRequest(context.TODO(), RequestOptions{TokenURL: "u"})
+		follower <- resp
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	cancel()
+	if err := <-leaderErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled for leader, got %v", err)
+	}
+
+	close(release)
+	if resp := <-follower; resp.AccessToken != "at" {
+		t.Errorf("follower should get the token despite leader cancellation, got '%s'", resp.AccessToken)
+	}
+}
@@ -0,0 +1,219 @@
+package clientcredentials
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Defaults for RetryPolicy.
+const (
+	DefaultRetryMaxAttempts = 3
+	DefaultRetryBackoffBase = 100 * time.Millisecond
+	DefaultRetryBackoffCap  = 5 * time.Second
+)
+
+// DefaultRetryableStatusCodes are the status codes retried when
+// RetryPolicy.RetryableStatusCodes is nil.
+var DefaultRetryableStatusCodes = []int{
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// RetryPolicy defines how failed token requests are retried.
+// Errors carrying an RFC 6749 error code (like invalid_client) are never
+// retried, regardless of the status code.
+type RetryPolicy struct {
+	// MaxAttempts is optional maximum number of attempts, including the first one.
+	// If zero, DefaultRetryMaxAttempts will be used.
+	MaxAttempts int
+
+	// BackoffBase is optional delay before the first retry. The delay doubles
+	// on each retry up to BackoffCap.
+	// If zero, DefaultRetryBackoffBase will be used.
+	BackoffBase time.Duration
+
+	// BackoffCap is optional maximum delay between attempts.
+	// If zero, DefaultRetryBackoffCap will be used.
+	BackoffCap time.Duration
+
+	// MaxRetryAfter is optional longest Retry-After delay honored. When the
+	// token endpoint asks to wait longer, the error is returned without retrying.
+	// If zero, BackoffCap will be used.
+	MaxRetryAfter time.Duration
+
+	// DisableJitter disables randomization of delays. By default, each delay
+	// is picked at random between half and all of the computed backoff.
+	DisableJitter bool
+
+	// RetryableStatusCodes is optional list of retryable status codes.
+	// If nil, DefaultRetryableStatusCodes will be used.
+	RetryableStatusCodes []int
+
+	// IsRetryableError is optional function to check if an error other than
+	// an *ErrorResponse is retryable.
+	// If nil, DefaultIsRetryableError will be used.
+	IsRetryableError func(err error) bool
+}
+
+// DefaultIsRetryableError reports transient network errors as retryable:
+// timeouts, including http.Client.Timeout, DNS failures, refused or reset
+// connections, unreachable hosts and connections closed early. Errors that
+// would fail again, like TLS certificate errors or an invalid URL, are not
+// retryable. Cancellation is decided by the caller's context, which
+// retries always check first.
+func DefaultIsRetryableError(err error) bool {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err // url.Error is a net.Error whatever the cause
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || isTransientErrno(err) {
+		return true
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// isOAuth2ErrorCode reports whether code is a token endpoint error code
+// defined by RFC 6749 section 5.2. Such errors are not transient.
+func isOAuth2ErrorCode(code string) bool {
+	switch code {
+	case ErrorCodeInvalidRequest, ErrorCodeInvalidClient, ErrorCodeInvalidGrant,
+		ErrorCodeUnauthorizedClient, ErrorCodeUnsupportedGrantType, ErrorCodeInvalidScope:
+		return true
+	}
+	return false
+}
+
+// retryable reports whether err is transient according to the policy.
+func (p *RetryPolicy) retryable(err error) bool {
+	var errResp *ErrorResponse
+	if errors.As(err, &errResp) {
+		if isOAuth2ErrorCode(errResp.Code) {
+			return false
+		}
+		codes := p.RetryableStatusCodes
+		if codes == nil {
+			codes = DefaultRetryableStatusCodes
+		}
+		return slices.Contains(codes, errResp.StatusCode)
+	}
+	isRetryable := p.IsRetryableError
+	if isRetryable == nil {
+		isRetryable = DefaultIsRetryableError
+	}
+	return isRetryable(err)
+}
+
+// backoff returns the delay before retry number attempt (starting at 1).
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	base := p.BackoffBase
+	if base == 0 {
+		base = DefaultRetryBackoffBase
+	}
+	limit := p.BackoffCap
+	if limit == 0 {
+		limit = DefaultRetryBackoffCap
+	}
+
+	d := base
+	for i := 1; i < attempt && d < limit; i++ {
+		d *= 2
+	}
+	d = min(d, limit)
+
+	if !p.DisableJitter && d > 1 {
+		d = d/2 + rand.N(d/2)
+	}
+	return d
+}
+
+// maxRetryAfter returns the longest Retry-After delay honored.
+func (p *RetryPolicy) maxRetryAfter() time.Duration {
+	if p.MaxRetryAfter != 0 {
+		return p.MaxRetryAfter
+	}
+	if p.BackoffCap != 0 {
+		return p.BackoffCap
+	}
+	return DefaultRetryBackoffCap
+}
+
+// retryWithPolicy calls fn until it succeeds, fails with a non-retryable
+// error, attempts are exhausted, or the context deadline would be exceeded.
+// A nil policy calls fn exactly once.
+func retryWithPolicy[T any](ctx context.Context, p *RetryPolicy, fn func() (T, error)) (T, error) {
+	result, err := fn()
+	if p == nil {
+		return result, err
+	}
+
+	maxAttempts := p.MaxAttempts
+	if maxAttempts == 0 {
+		maxAttempts = DefaultRetryMaxAttempts
+	}
+
+	for attempt := 1; err != nil && attempt < maxAttempts && ctx.Err() == nil && p.retryable(err); attempt++ {
+		wait := p.backoff(attempt)
+		if retryAfter, found := retryAfterDelay(err, time.Now()); found {
+			if retryAfter > p.maxRetryAfter() {
+				break // the server asks to wait longer than allowed
+			}
+			wait = retryAfter
+		}
+
+		if deadline, hasDeadline := ctx.Deadline(); hasDeadline && time.Now().Add(wait).After(deadline) {
+			break // waiting would exceed the deadline
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result, err
+		case <-timer.C:
+		}
+
+		result, err = fn()
+	}
+
+	return result, err
+}
+
+// retryAfterDelay extracts the Retry-After header from an *ErrorResponse.
+// Both delay-seconds and HTTP-date forms are supported.
+func retryAfterDelay(err error, now time.Time) (time.Duration, bool) {
+	var errResp *ErrorResponse
+	if !errors.As(err, &errResp) {
+		return 0, false
+	}
+	value := errResp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, errConv := strconv.ParseInt(value, 10, 64); errConv == nil || errors.Is(errConv, strconv.ErrRange) {
+		// clamp before converting to avoid overflow; the limit is well
+		// past any sensible MaxRetryAfter, so huge values are too long
+		seconds = min(max(seconds, 0), math.MaxInt32)
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, errParse := http.ParseTime(value); errParse == nil {
+		return max(date.Sub(now), 0), true
+	}
+	return 0, false
+}
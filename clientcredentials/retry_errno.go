@@ -0,0 +1,14 @@
+//go:build !plan9
+
+package clientcredentials
+
+import (
+	"errors"
+	"syscall"
+)
+
+// isTransientErrno reports connection errors that may succeed on retry.
+func isTransientErrno(err error) bool {
+	return errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EHOSTUNREACH) || errors.Is(err, syscall.ENETUNREACH)
+}
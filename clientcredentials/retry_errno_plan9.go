@@ -0,0 +1,7 @@
+package clientcredentials
+
+// isTransientErrno reports connection errors that may succeed on retry.
+// Plan 9 reports network errors as strings, not errno values.
+func isTransientErrno(_ error) bool {
+	return false
+}
@@ -0,0 +1,30 @@
+//go:build !plan9
+
+package clientcredentials
+
+import (
+	"net"
+	"net/url"
+	"syscall"
+	"testing"
+)
+
+func TestIsTransientErrno(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"refused", syscall.ECONNREFUSED, true},
+		{"reset", syscall.ECONNRESET, true},
+		{"host unreachable", syscall.EHOSTUNREACH, true},
+		{"network unreachable", syscall.ENETUNREACH, true},
+		{"permission", syscall.EACCES, false},
+	}
+	for _, tt := range tests {
+		err := &url.Error{Op: "Post", URL: "x", Err: &net.OpError{Op: "dial", Err: tt.err}}
+		if got := DefaultIsRetryableError(err); got != tt.want {
+			t.Errorf("%s: expected %t, got %t", tt.name, tt.want, got)
+		}
+	}
+}
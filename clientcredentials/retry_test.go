@@ -0,0 +1,290 @@
+package clientcredentials
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newFlakyTokenServer fails the first failures requests with status and body.
+func newFlakyTokenServer(t *testing.T, failures int32, status int, header, body string, count *int32) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if atomic.AddInt32(count, 1) <= failures {
+			if header != "" {
+				w.Header().Set("Retry-After", header)
+			}
+			w.WriteHeader(status)
+			w.Write([]byte(body))
+			return
+		}
+		w.Write([]byte(EncodeResponseBody("at", "", 60)))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRetry(t *testing.T) {
+	var count int32
+	server := newFlakyTokenServer(t, 2, http.StatusServiceUnavailable, "", "", &count)
+
+	resp, err := SendRequest(context.TODO(), RequestOptions{
+		TokenURL: server.URL,
+		Retry:    &RetryPolicy{BackoffBase: time.Millisecond},
+	})
+	if err != nil {
+		t.Fatalf("send request: %v", err)
+	}
+	if resp.AccessToken != "at" {
+		t.Errorf("expected access token at, got %s", resp.AccessToken)
+	}
+	if got := atomic.LoadInt32(&count); got != 3 {
+		t.Errorf("expected 3 attempts, got %d", got)
+	}
+}
+
+func TestRetryExhausted(t *testing.T) {
+	var count int32
+	server := newFlakyTokenServer(t, 10, http.StatusTooManyRequests, "0", "", &count)
+
+	_, err := SendRequest(context.TODO(), RequestOptions{
+		TokenURL: server.URL,
+		Retry:    &RetryPolicy{MaxAttempts: 4},
+	})
+
+	var errResp *ErrorResponse
+	if !errors.As(err, &errResp) || errResp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 error, got %v", err)
+	}
+	if got := atomic.LoadInt32(&count); got != 4 {
+		t.Errorf("expected 4 attempts, got %d", got)
+	}
+}
+
+func TestRetryNonTransient(t *testing.T) {
+	var count int32
+	server := newFlakyTokenServer(t, 10, http.StatusServiceUnavailable, "", `{"error":"invalid_client"}`, &count)
+
+	_, err := SendRequest(context.TODO(), RequestOptions{
+		TokenURL: server.URL,
+		Retry:    &RetryPolicy{BackoffBase: time.Millisecond},
+	})
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if got := atomic.LoadInt32(&count); got != 1 {
+		t.Errorf("expected invalid_client not to be retried, got %d attempts", got)
+	}
+}
+
+func TestRetryAfterDeadline(t *testing.T) {
+	var count int32
+	server := newFlakyTokenServer(t, 10, http.StatusServiceUnavailable, "30", "", &count)
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+	defer cancel()
+
+	begin := time.Now()
+	_, err := SendRequest(ctx, RequestOptions{
+		TokenURL: server.URL,
+		Retry:    &RetryPolicy{},
+	})
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if elapsed := time.Since(begin); elapsed > 500*time.Millisecond {
+		t.Errorf("expected immediate failure when Retry-After exceeds deadline, took %v", elapsed)
+	}
+	if got := atomic.LoadInt32(&count); got != 1 {
+		t.Errorf("expected 1 attempt, got %d", got)
+	}
+}
+
+func TestRetryAfterTooLong(t *testing.T) {
+	var count int32
+	server := newFlakyTokenServer(t, 10, http.StatusServiceUnavailable, "3600", "", &count)
+
+	begin := time.Now()
+	_, err := SendRequest(context.TODO(), RequestOptions{
+		TokenURL: server.URL,
+		Retry:    &RetryPolicy{MaxRetryAfter: time.Minute},
+	})
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if elapsed := time.Since(begin); elapsed > 500*time.Millisecond {
+		t.Errorf("expected immediate failure when Retry-After exceeds MaxRetryAfter, took %v", elapsed)
+	}
+	if got := atomic.LoadInt32(&count); got != 1 {
+		t.Errorf("expected 1 attempt, got %d", got)
+	}
+}
+
+func TestRetryClientTimeout(t *testing.T) {
+	var count int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if atomic.AddInt32(&count, 1) == 1 {
+			<-release // hang past the client timeout
+			return
+		}
+		w.Write([]byte(EncodeResponseBody("at", "", 60)))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	resp, err := SendRequest(context.TODO(), RequestOptions{
+		TokenURL:   server.URL,
+		HTTPClient: &http.Client{Timeout: 100 * time.Millisecond},
+		Retry:      &RetryPolicy{BackoffBase: time.Millisecond},
+	})
+	if err != nil {
+		t.Fatalf("expected client timeout to be retried: %v", err)
+	}
+	if resp.AccessToken != "at" {
+		t.Errorf("expected access token at, got %s", resp.AccessToken)
+	}
+	if got := atomic.LoadInt32(&count); got != 2 {
+		t.Errorf("expected 2 attempts, got %d", got)
+	}
+}
+
+func TestRetryAfterHuge(t *testing.T) {
+	var count int32
+	server := newFlakyTokenServer(t, 10, http.StatusServiceUnavailable, "10000000000", "", &count)
+
+	if _, err := SendRequest(context.TODO(), RequestOptions{
+		TokenURL: server.URL,
+		Retry:    &RetryPolicy{MaxAttempts: 5},
+	}); err == nil {
+		t.Fatalf("expected error")
+	}
+	if got := atomic.LoadInt32(&count); got != 1 {
+		t.Errorf("expected huge Retry-After to stop retrying, got %d attempts", got)
+	}
+}
+
+func TestRetryNetworkError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	tokenURL := server.URL
+	server.Close() // connection refused
+
+	var attempts int32
+	p := &RetryPolicy{
+		BackoffBase: time.Millisecond,
+		IsRetryableError: func(err error) bool {
+			atomic.AddInt32(&attempts, 1)
+			return DefaultIsRetryableError(err)
+		},
+	}
+
+	if _, err := SendRequest(context.TODO(), RequestOptions{TokenURL: tokenURL, Retry: p}); err == nil {
+		t.Fatalf("expected error")
+	}
+	if got := atomic.LoadInt32(&attempts); got != DefaultRetryMaxAttempts-1 {
+		t.Errorf("expected %d retry checks, got %d", DefaultRetryMaxAttempts-1, got)
+	}
+}
+
+func TestDefaultIsRetryableError(t *testing.T) {
+	refused := httptest.NewServer(http.NotFoundHandler())
+	refusedURL := refused.URL
+	refused.Close()
+
+	untrusted := httptest.NewTLSServer(http.NotFoundHandler())
+	defer untrusted.Close()
+
+	hanging := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer hanging.Close()
+
+	get := func(url string) error {
+		t.Helper()
+		client := &http.Client{Timeout: 5 * time.Second}
+		if url == hanging.URL {
+			client.Timeout = 100 * time.Millisecond
+		}
+		resp, err := client.Get(url)
+		if err == nil {
+			resp.Body.Close()
+			t.Fatalf("get %s: expected error", url)
+		}
+		return err
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"connection refused", get(refusedURL), true},
+		{"client timeout", get(hanging.URL), true},
+		{"no such host", get("http://idp.invalid/token"), true},
+		{"unknown authority", get(untrusted.URL), false},
+		{"unsupported scheme", get("ftp://localhost/token"), false},
+		{"invalid url", get("http://[::1"), false},
+		{"timeout", &url.Error{Op: "Post", URL: "x", Err: &net.OpError{Op: "dial", Err: os.ErrDeadlineExceeded}}, true},
+		{"eof", &url.Error{Op: "Post", URL: "x", Err: io.EOF}, true},
+		{"unexpected eof", io.ErrUnexpectedEOF, true},
+		{"canceled", &url.Error{Op: "Post", URL: "x", Err: context.Canceled}, false},
+		{"deadline", context.DeadlineExceeded, true}, // retries check the caller's context
+		{"plain", errors.New("plain"), false},
+	}
+	for _, tt := range tests {
+		if got := DefaultIsRetryableError(tt.err); got != tt.want {
+			t.Errorf("%s: expected %t, got %t (%v)", tt.name, tt.want, got, tt.err)
+		}
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	p := &RetryPolicy{BackoffBase: 100 * time.Millisecond, BackoffCap: time.Second, DisableJitter: true}
+
+	for attempt, want := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		if got := p.backoff(attempt + 1); got != want*time.Millisecond {
+			t.Errorf("attempt %d: expected %v, got %v", attempt+1, want*time.Millisecond, got)
+		}
+	}
+
+	p.DisableJitter = false
+	for range 100 {
+		if got := p.backoff(2); got < 100*time.Millisecond || got >= 200*time.Millisecond {
+			t.Fatalf("jittered backoff out of range: %v", got)
+		}
+	}
+}
+
+func TestRetryAfterDelay(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	newErr := func(value string) error {
+		return &ErrorResponse{Header: http.Header{"Retry-After": {value}}, Err: errors.New("x")}
+	}
+
+	if d, found := retryAfterDelay(newErr("7"), now); !found || d != 7*time.Second {
+		t.Errorf("seconds: got %v %t", d, found)
+	}
+	if d, found := retryAfterDelay(newErr(now.Add(3*time.Second).Format(http.TimeFormat)), now); !found || d != 3*time.Second {
+		t.Errorf("http-date: got %v %t", d, found)
+	}
+	for _, huge := range []string{"10000000000", "99999999999999999999"} {
+		if d, found := retryAfterDelay(newErr(huge), now); !found || d < 24*time.Hour {
+			t.Errorf("huge %s: expected a long delay, got %v %t", huge, d, found)
+		}
+	}
+	if _, found := retryAfterDelay(newErr("soon"), now); found {
+		t.Errorf("invalid value: expected not found")
+	}
+	if _, found := retryAfterDelay(errors.New("plain"), now); found {
+		t.Errorf("plain error: expected not found")
+	}
+}
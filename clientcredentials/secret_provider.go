@@ -0,0 +1,195 @@
+package clientcredentials
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSecretCommandTTL is the default time CommandSecretProvider caches
+// the command output.
+const DefaultSecretCommandTTL = 5 * time.Minute
+
+// SecretProvider supplies the client secret, allowing it to be rotated
+// without restarting the process. ClientSecret is called on every token
+// request, so implementations should cache expensive lookups.
+// Implementations must be safe for concurrent use by multiple goroutines.
+type SecretProvider interface {
+	// ClientSecret returns the current client secret.
+	ClientSecret(ctx context.Context) (string, error)
+
+	// Invalidate discards any cached secret. It is called when the token
+	// endpoint rejects the secret with invalid_client.
+	Invalidate()
+}
+
+// EnvSecretProvider reads the client secret from an environment variable.
+type EnvSecretProvider struct {
+	name string
+}
+
+// NewEnvSecretProvider creates an EnvSecretProvider for variable name.
+func NewEnvSecretProvider(name string) *EnvSecretProvider {
+	return &EnvSecretProvider{name: name}
+}
+
+// ClientSecret implements SecretProvider.
+func (p *EnvSecretProvider) ClientSecret(_ context.Context) (string, error) {
+	secret := os.Getenv(p.name)
+	if secret == "" {
+		return "", fmt.Errorf("oauth2clientcredentials: client secret: environment variable %s is empty", p.name)
+	}
+	return secret, nil
+}
+
+// Invalidate implements SecretProvider. The variable is read on every call.
+func (p *EnvSecretProvider) Invalidate() {}
+
+// FileSecretProvider reads the client secret from a file, for example a
+// Kubernetes secret mount. The file is read again whenever its
+// modification time or size changes. Trailing newlines are removed.
+type FileSecretProvider struct {
+	path string
+
+	mutex   sync.Mutex
+	secret  string
+	modTime time.Time
+	size    int64
+	valid   bool
+}
+
+// NewFileSecretProvider creates a FileSecretProvider for path.
+func NewFileSecretProvider(path string) *FileSecretProvider {
+	return &FileSecretProvider{path: path}
+}
+
+// ClientSecret implements SecretProvider.
+func (p *FileSecretProvider) ClientSecret(_ context.Context) (string, error) {
+	// os.Stat follows the symlinks Kubernetes swaps on update
+	info, errStat := os.Stat(p.path)
+	if errStat != nil {
+		return "", fmt.Errorf("oauth2clientcredentials: client secret: %w", errStat)
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.valid && info.ModTime().Equal(p.modTime) && info.Size() == p.size {
+		return p.secret, nil
+	}
+
+	data, errRead := os.ReadFile(p.path)
+	if errRead != nil {
+		return "", fmt.Errorf("oauth2clientcredentials: client secret: %w", errRead)
+	}
+
+	secret := strings.TrimRight(string(data), "\r\n")
+	if secret == "" {
+		return "", fmt.Errorf("oauth2clientcredentials: client secret: file %s is empty", p.path)
+	}
+
+	p.secret = secret
+	p.modTime = info.ModTime()
+	p.size = info.Size()
+	p.valid = true
+
+	return secret, nil
+}
+
+// Invalidate implements SecretProvider.
+func (p *FileSecretProvider) Invalidate() {
+	p.mutex.Lock()
+	p.valid = false
+	p.mutex.Unlock()
+}
+
+// CommandSecretProviderOptions contains options for creating a
+// CommandSecretProvider.
+type CommandSecretProviderOptions struct {
+	// Command is the program and its arguments, for example
+	// []string{"vault", "read", "-field=secret", "secret/app"}.
+	Command []string
+
+	// TTL is optional time the command output is cached.
+	// If zero, DefaultSecretCommandTTL will be used.
+	TTL time.Duration
+}
+
+// CommandSecretProvider obtains the client secret from the standard output
+// of an external command, for example a secret manager CLI. Surrounding
+// whitespace is removed.
+type CommandSecretProvider struct {
+	options CommandSecretProviderOptions
+
+	mutex  sync.Mutex
+	secret string
+	expiry time.Time
+
+	now func() time.Time
+}
+
+// NewCommandSecretProvider creates a CommandSecretProvider.
+func NewCommandSecretProvider(options CommandSecretProviderOptions) *CommandSecretProvider {
+	if options.TTL == 0 {
+		options.TTL = DefaultSecretCommandTTL
+	}
+	return &CommandSecretProvider{options: options, now: time.Now}
+}
+
+// ClientSecret implements SecretProvider.
+func (p *CommandSecretProvider) ClientSecret(ctx context.Context) (string, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.secret != "" && p.now().Before(p.expiry) {
+		return p.secret, nil
+	}
+
+	if len(p.options.Command) == 0 {
+		return "", errors.New("oauth2clientcredentials: client secret: empty command")
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.options.Command[0], p.options.Command[1:]...)
+	cmd.Stderr = &stderr
+
+	out, errRun := cmd.Output()
+	if errRun != nil {
+		return "", fmt.Errorf("oauth2clientcredentials: client secret: command %s: %w: %s",
+			p.options.Command[0], errRun, strings.TrimSpace(stderr.String()))
+	}
+
+	secret := strings.TrimSpace(string(out))
+	if secret == "" {
+		return "", fmt.Errorf("oauth2clientcredentials: client secret: command %s returned empty output", p.options.Command[0])
+	}
+
+	p.secret = secret
+	p.expiry = p.now().Add(p.options.TTL)
+
+	return secret, nil
+}
+
+// Invalidate implements SecretProvider.
+func (p *CommandSecretProvider) Invalidate() {
+	p.mutex.Lock()
+	p.secret = ""
+	p.mutex.Unlock()
+}
+
+// usesClientSecret reports whether the auth method sends the client secret.
+func usesClientSecret(method string) bool {
+	return method == "" || method == AuthMethodClientSecretPost || method == AuthMethodClientSecretBasic
+}
+
+// isInvalidClient reports whether err is an invalid_client error response.
+func isInvalidClient(err error) bool {
+	var errResp *ErrorResponse
+	return errors.As(err, &errResp) && errResp.Code == ErrorCodeInvalidClient
+}
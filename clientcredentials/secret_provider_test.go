@@ -0,0 +1,171 @@
+package clientcredentials
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEnvSecretProvider(t *testing.T) {
+	t.Setenv("TEST_CLIENT_SECRET", "s1")
+
+	p := NewEnvSecretProvider("TEST_CLIENT_SECRET")
+
+	if secret, err := p.ClientSecret(context.TODO()); err != nil || secret != "s1" {
+		t.Errorf("expected s1, got '%s' %v", secret, err)
+	}
+
+	t.Setenv("TEST_CLIENT_SECRET", "s2")
+	if secret, _ := p.ClientSecret(context.TODO()); secret != "s2" {
+		t.Errorf("expected s2, got '%s'", secret)
+	}
+
+	t.Setenv("TEST_CLIENT_SECRET", "")
+	if _, err := p.ClientSecret(context.TODO()); err == nil {
+		t.Errorf("expected error for empty variable")
+	}
+}
+
+func TestFileSecretProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "client-secret")
+
+	if err := os.WriteFile(path, []byte("first\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewFileSecretProvider(path)
+
+	if secret, err := p.ClientSecret(context.TODO()); err != nil || secret != "first" {
+		t.Errorf("expected first, got '%s' %v", secret, err)
+	}
+
+	// rotated secret, as written by a Kubernetes secret update
+	if err := os.WriteFile(path, []byte("rotated\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if secret, _ := p.ClientSecret(context.TODO()); secret != "rotated" {
+		t.Errorf("expected rotated, got '%s'", secret)
+	}
+
+	// same size and time: cached until invalidated
+	info, _ := os.Stat(path)
+	if err := os.WriteFile(path, []byte("another\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(path, info.ModTime(), info.ModTime())
+	if secret, _ := p.ClientSecret(context.TODO()); secret != "rotated" {
+		t.Errorf("expected cached rotated, got '%s'", secret)
+	}
+	p.Invalidate()
+	if secret, _ := p.ClientSecret(context.TODO()); secret != "another" {
+		t.Errorf("expected another after invalidate, got '%s'", secret)
+	}
+
+	os.Remove(path)
+	if _, err := p.ClientSecret(context.TODO()); err == nil {
+		t.Errorf("expected error for missing file")
+	}
+}
+
+func TestCommandSecretProvider(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires cat")
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "secret")
+	if err := os.WriteFile(path, []byte("  from-command\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewCommandSecretProvider(CommandSecretProviderOptions{
+		Command: []string{"cat", path},
+		TTL:     time.Minute,
+	})
+	now := time.Now()
+	p.now = func() time.Time { return now }
+
+	if secret, err := p.ClientSecret(context.TODO()); err != nil || secret != "from-command" {
+		t.Fatalf("expected from-command, got '%s' %v", secret, err)
+	}
+
+	os.WriteFile(path, []byte("next"), 0600)
+
+	if secret, _ := p.ClientSecret(context.TODO()); secret != "from-command" {
+		t.Errorf("expected cached secret, got '%s'", secret)
+	}
+
+	now = now.Add(time.Minute)
+	if secret, _ := p.ClientSecret(context.TODO()); secret != "next" {
+		t.Errorf("expected next after ttl, got '%s'", secret)
+	}
+
+	failing := NewCommandSecretProvider(CommandSecretProviderOptions{
+		Command: []string{"cat", filepath.Join(dir, "missing")},
+	})
+	if _, err := failing.ClientSecret(context.TODO()); err == nil {
+		t.Errorf("expected error for failing command")
+	}
+}
+
+// rotatingSecretProvider returns "old" until invalidated.
+type rotatingSecretProvider struct {
+	invalidated atomic.Bool
+}
+
+func (p *rotatingSecretProvider) ClientSecret(_ context.Context) (string, error) {
+	if p.invalidated.Load() {
+		return "new", nil
+	}
+	return "old", nil
+}
+
+func (p *rotatingSecretProvider) Invalidate() { p.invalidated.Store(true) }
+
+func TestSendRequestSecretProviderInvalidClient(t *testing.T) {
+	var count atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count.Add(1)
+		req, err := DecodeRequestBody(r)
+		if err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		if req.ClientSecret != "new" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"invalid_client"}`))
+			return
+		}
+		w.Write([]byte(`{"access_token":"at","token_type":"Bearer"}`))
+	}))
+	defer server.Close()
+
+	for _, method := range []string{AuthMethodClientSecretPost, AuthMethodClientSecretBasic} {
+		count.Store(0)
+		provider := &rotatingSecretProvider{}
+
+		resp, err := SendRequest(context.TODO(), RequestOptions{
+			TokenURL:       server.URL,
+			ClientID:       "id",
+			ClientSecret:   "ignored",
+			AuthMethod:     method,
+			SecretProvider: provider,
+		})
+		if err != nil {
+			t.Errorf("%s: send request: %v", method, err)
+			continue
+		}
+		if resp.AccessToken != "at" {
+			t.Errorf("%s: unexpected access_token: %s", method, resp.AccessToken)
+		}
+		if count.Load() != 2 {
+			t.Errorf("%s: expected 2 requests, got %d", method, count.Load())
+		}
+	}
+}
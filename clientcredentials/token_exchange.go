@@ -0,0 +1,74 @@
+package clientcredentials
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sugawarayuuta/sonnet"
+)
+
+// GrantTypeTokenExchange is the grant_type for token exchange (RFC 8693).
+const GrantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+
+// Token type identifiers defined by RFC 8693 section 3.
+const (
+	TokenTypeAccessToken  = "urn:ietf:params:oauth:token-type:access_token"
+	TokenTypeRefreshToken = "urn:ietf:params:oauth:token-type:refresh_token"
+	TokenTypeIDToken      = "urn:ietf:params:oauth:token-type:id_token"
+	TokenTypeSAML1        = "urn:ietf:params:oauth:token-type:saml1"
+	TokenTypeSAML2        = "urn:ietf:params:oauth:token-type:saml2"
+	TokenTypeJWT          = "urn:ietf:params:oauth:token-type:jwt"
+)
+
+// TokenExchangeOptions contains options for sending a token exchange request.
+type TokenExchangeOptions struct {
+	// RequestOptions provides the token endpoint, client authentication,
+	// scope, audience, resource and HTTP settings.
+	RequestOptions RequestOptions
+
+	// SubjectToken and SubjectTokenType are required.
+	SubjectToken     string
+	SubjectTokenType string
+
+	// ActorToken and ActorTokenType are optional. ActorTokenType is required
+	// when ActorToken is set.
+	ActorToken     string
+	ActorTokenType string
+
+	// RequestedTokenType is optional type of the requested token.
+	RequestedTokenType string
+}
+
+// SendTokenExchange sends a token exchange request (RFC 8693) and returns
+// the response. The issued_token_type is available in Response.IssuedTokenType.
+func SendTokenExchange(ctx context.Context, options TokenExchangeOptions) (Response, error) {
+	if options.SubjectToken == "" || options.SubjectTokenType == "" {
+		return Response{}, fmt.Errorf("oauth2clientcredentials.SendTokenExchange: subject_token and subject_token_type are required")
+	}
+	if options.ActorToken != "" && options.ActorTokenType == "" {
+		return Response{}, fmt.Errorf("oauth2clientcredentials.SendTokenExchange: actor_token_type is required with actor_token")
+	}
+
+	ro := options.RequestOptions
+
+	form := Request{
+		GrantType:          GrantTypeTokenExchange,
+		Scope:              ro.Scope,
+		Audience:           ro.Audience,
+		Resource:           ro.Resource,
+		Extra:              ro.ExtraParams,
+		SubjectToken:       options.SubjectToken,
+		SubjectTokenType:   options.SubjectTokenType,
+		ActorToken:         options.ActorToken,
+		ActorTokenType:     options.ActorTokenType,
+		RequestedTokenType: options.RequestedTokenType,
+	}
+
+	return sendTokenRequest(ctx, ro, tokenRequest{form: form, caller: "SendTokenExchange"})
+}
+
+// EncodeResponse encodes a token response, including optional fields
+// like issued_token_type.
+func EncodeResponse(resp Response) ([]byte, error) {
+	return sonnet.Marshal(resp)
+}
@@ -0,0 +1,105 @@
+package clientcredentials
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSendTokenExchange(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, err := DecodeRequestBody(r)
+		if err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		if req.GrantType != GrantTypeTokenExchange {
+			t.Errorf("unexpected grant_type: %s", req.GrantType)
+		}
+		if req.SubjectToken != "user-token" || req.SubjectTokenType != TokenTypeAccessToken {
+			t.Errorf("unexpected subject: %s %s", req.SubjectToken, req.SubjectTokenType)
+		}
+		if req.ActorToken != "actor-token" || req.ActorTokenType != TokenTypeJWT {
+			t.Errorf("unexpected actor: %s %s", req.ActorToken, req.ActorTokenType)
+		}
+		if req.RequestedTokenType != TokenTypeAccessToken {
+			t.Errorf("unexpected requested_token_type: %s", req.RequestedTokenType)
+		}
+		if req.Audience != "downstream" {
+			t.Errorf("unexpected audience: %s", req.Audience)
+		}
+		if req.ClientID != "gateway" || req.ClientSecret != "secret" {
+			t.Errorf("unexpected client credentials: %s %s", req.ClientID, req.ClientSecret)
+		}
+		if len(req.Extra) != 0 {
+			t.Errorf("unexpected extra parameters: %v", req.Extra)
+		}
+
+		body, err := EncodeResponse(Response{
+			AccessToken:     "downstream-token",
+			TokenType:       "N_A",
+			ExpiresIn:       60,
+			IssuedTokenType: TokenTypeAccessToken,
+		})
+		if err != nil {
+			t.Fatalf("encode response: %v", err)
+		}
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	resp, err := SendTokenExchange(context.TODO(), TokenExchangeOptions{
+		RequestOptions: RequestOptions{
+			TokenURL:     server.URL,
+			ClientID:     "gateway",
+			ClientSecret: "secret",
+			Audience:     "downstream",
+		},
+		SubjectToken:       "user-token",
+		SubjectTokenType:   TokenTypeAccessToken,
+		ActorToken:         "actor-token",
+		ActorTokenType:     TokenTypeJWT,
+		RequestedTokenType: TokenTypeAccessToken,
+	})
+	if err != nil {
+		t.Fatalf("send token exchange: %v", err)
+	}
+
+	if resp.AccessToken != "downstream-token" {
+		t.Errorf("unexpected access_token: %s", resp.AccessToken)
+	}
+	if resp.IssuedTokenType != TokenTypeAccessToken {
+		t.Errorf("unexpected issued_token_type: %s", resp.IssuedTokenType)
+	}
+}
+
+func TestSendTokenExchangeValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		options TokenExchangeOptions
+	}{
+		{"missing subject", TokenExchangeOptions{SubjectTokenType: TokenTypeJWT}},
+		{"missing subject type", TokenExchangeOptions{SubjectToken: "t"}},
+		{"actor without type", TokenExchangeOptions{SubjectToken: "t", SubjectTokenType: TokenTypeJWT, ActorToken: "a"}},
+	}
+	for _, tt := range tests {
+		if _, err := SendTokenExchange(context.TODO(), tt.options); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+}
+
+func TestDecodeIssuedTokenType(t *testing.T) {
+	data := []byte(`{"access_token":"at","token_type":"N_A","issued_token_type":"` + TokenTypeJWT + `"}`)
+
+	for name, decode := range decoderTable {
+		resp, err := decode(data)
+		if err != nil {
+			t.Errorf("%s: decode: %v", name, err)
+			continue
+		}
+		if resp.IssuedTokenType != TokenTypeJWT {
+			t.Errorf("%s: expected issued_token_type %s, got '%s'", name, TokenTypeJWT, resp.IssuedTokenType)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package clientcredentials
+
+import (
+	"container/list"
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults for TokenManagerOptions.
+const (
+	DefaultTokenManagerMaxEntries  = 1000
+	DefaultTokenManagerIdleTimeout = 30 * time.Minute
+)
+
+// TokenManagerOptions contains options for creating a TokenManager.
+type TokenManagerOptions struct {
+	// MaxEntries is optional maximum number of cached tokens. When exceeded,
+	// the least recently used token is evicted.
+	// If zero, DefaultTokenManagerMaxEntries will be used.
+	MaxEntries int
+
+	// IdleTimeout is optional duration after which an unused token is evicted.
+	// If zero, DefaultTokenManagerIdleTimeout will be used.
+	IdleTimeout time.Duration
+
+	// ExpiryMargin is optional safety margin subtracted from expires_in.
+	// If zero, DefaultExpiryMargin will be used.
+	ExpiryMargin time.Duration
+
+	// Fetch is optional function used to fetch tokens.
+	// If nil, SendRequest will be used.
+	Fetch FetchFunc
+}
+
+// TokenManager caches one token per (TokenURL, ClientID, scope set,
+// Audience, Resource, ExtraParams, ExtraHeaders) key, for callers fetching tokens on behalf of many
+// clients. Scopes are normalized, so "b a" and "a b a" share a token.
+// Each key is refreshed independently.
+// TokenManager is safe for concurrent use by multiple goroutines.
+type TokenManager struct {
+	options TokenManagerOptions
+
+	mutex   sync.Mutex
+	lru     *list.List // front is most recently used
+	entries map[string]*list.Element
+
+	now func() time.Time
+}
+
+type managerEntry struct {
+	key      string
+	source   *TokenSource
+	lastUsed time.Time
+}
+
+// NewTokenManager creates a TokenManager.
+func NewTokenManager(options TokenManagerOptions) *TokenManager {
+	if options.MaxEntries == 0 {
+		options.MaxEntries = DefaultTokenManagerMaxEntries
+	}
+	if options.IdleTimeout == 0 {
+		options.IdleTimeout = DefaultTokenManagerIdleTimeout
+	}
+	return &TokenManager{
+		options: options,
+		lru:     list.New(),
+		entries: map[string]*list.Element{},
+		now:     time.Now,
+	}
+}
+
+// Token returns the cached token for options, fetching a new one when it
+// is missing or near expiry.
+func (m *TokenManager) Token(ctx context.Context, options RequestOptions) (Response, error) {
+	return m.source(options).Token(ctx)
+}
+
+// Invalidate discards the cached token for options.
+func (m *TokenManager) Invalidate(options RequestOptions) {
+	key := managerKey(options)
+
+	m.mutex.Lock()
+	elem, found := m.entries[key]
+	m.mutex.Unlock()
+
+	if found {
+		elem.Value.(*managerEntry).source.Invalidate()
+	}
+}
+
+// Len returns the number of cached entries.
+func (m *TokenManager) Len() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.lru.Len()
+}
+
+// source returns the TokenSource for options, creating it if needed.
+func (m *TokenManager) source(options RequestOptions) *TokenSource {
+	key := managerKey(options)
+	now := m.now()
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.evictIdleLocked(now)
+
+	if elem, found := m.entries[key]; found {
+		entry := elem.Value.(*managerEntry)
+		entry.lastUsed = now
+		m.lru.MoveToFront(elem)
+		return entry.source
+	}
+
+	source := NewTokenSource(TokenSourceOptions{
+		RequestOptions: options,
+		ExpiryMargin:   m.options.ExpiryMargin,
+		Fetch:          m.options.Fetch,
+	})
+	source.now = m.now
+
+	m.entries[key] = m.lru.PushFront(&managerEntry{key: key, source: source, lastUsed: now})
+
+	for m.lru.Len() > m.options.MaxEntries {
+		m.removeLocked(m.lru.Back())
+	}
+
+	return source
+}
+
+// evictIdleLocked removes entries unused for longer than IdleTimeout.
+// Entries are ordered by last use, so it stops at the first active one.
+func (m *TokenManager) evictIdleLocked(now time.Time) {
+	for elem := m.lru.Back(); elem != nil; elem = m.lru.Back() {
+		if now.Sub(elem.Value.(*managerEntry).lastUsed) < m.options.IdleTimeout {
+			return
+		}
+		m.removeLocked(elem)
+	}
+}
+
+func (m *TokenManager) removeLocked(elem *list.Element) {
+	m.lru.Remove(elem)
+	delete(m.entries, elem.Value.(*managerEntry).key)
+}
+
+// managerKey identifies the token for options in a TokenManager.
+func managerKey(options RequestOptions) string {
+	resource := slices.Clone(options.Resource)
+	slices.Sort(resource)
+	var jkt string
+	if options.DPoP != nil {
+		jkt = options.DPoP.Thumbprint() // tokens are bound to the key
+	}
+	fields := append([]string{tokenURLKey(options), options.ClientID,
+		normalizeScope(options.Scope), options.Audience, jkt, extraKey(options)}, resource...)
+	return strings.Join(fields, "\x00")
+}
+
+// normalizeScope sorts and deduplicates space-delimited scopes.
+func normalizeScope(scope string) string {
+	scopes := strings.Fields(scope)
+	slices.Sort(scopes)
+	return strings.Join(slices.Compact(scopes), " ")
+}
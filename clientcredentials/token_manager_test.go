@@ -0,0 +1,128 @@
+package clientcredentials
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newCountingFetch(count *int32) FetchFunc {
+	return func(_ context.Context, options RequestOptions) (Response, error) {
+		atomic.AddInt32(count, 1)
+		return Response{AccessToken: options.ClientID + ":" + options.Scope, ExpiresIn: 3600}, nil
+	}
+}
+
+func TestTokenManager(t *testing.T) {
+	var count int32
+	m := NewTokenManager(TokenManagerOptions{Fetch: newCountingFetch(&count)})
+
+	ctx := context.TODO()
+
+	tok, err := m.Token(ctx, RequestOptions{TokenURL: "u", ClientID: "a", Scope: "read write"})
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+	if tok.AccessToken != "a:read write" {
+		t.Errorf("unexpected token: %s", tok.AccessToken)
+	}
+
+	// same scope set in different order shares the token
+	m.Token(ctx, RequestOptions{TokenURL: "u", ClientID: "a", Scope: "write  read read"})
+	if got := atomic.LoadInt32(&count); got != 1 {
+		t.Errorf("expected 1 fetch for equivalent scopes, got %d", got)
+	}
+
+	// distinct client, scope and audience get their own tokens
+	m.Token(ctx, RequestOptions{TokenURL: "u", ClientID: "b", Scope: "read write"})
+	m.Token(ctx, RequestOptions{TokenURL: "u", ClientID: "a", Scope: "read"})
+	m.Token(ctx, RequestOptions{TokenURL: "u", ClientID: "a", Scope: "read", Audience: "x"})
+	if got := atomic.LoadInt32(&count); got != 4 {
+		t.Errorf("expected 4 fetches, got %d", got)
+	}
+	if m.Len() != 4 {
+		t.Errorf("expected 4 entries, got %d", m.Len())
+	}
+
+	// tenants selected by extra parameters or headers are not merged
+	m.Token(ctx, RequestOptions{TokenURL: "u", ClientID: "a", Scope: "read", ExtraParams: url.Values{"tenant": {"t1"}}})
+	m.Token(ctx, RequestOptions{TokenURL: "u", ClientID: "a", Scope: "read", ExtraParams: url.Values{"tenant": {"t2"}}})
+	m.Token(ctx, RequestOptions{TokenURL: "u", ClientID: "a", Scope: "read", ExtraHeaders: http.Header{"X-Tenant": {"t1"}}})
+	if got := atomic.LoadInt32(&count); got != 7 {
+		t.Errorf("expected 7 fetches with distinct tenants, got %d", got)
+	}
+
+	m.Invalidate(RequestOptions{TokenURL: "u", ClientID: "b", Scope: "write read"})
+	m.Token(ctx, RequestOptions{TokenURL: "u", ClientID: "b", Scope: "read write"})
+	if got := atomic.LoadInt32(&count); got != 8 {
+		t.Errorf("expected refetch after invalidate, got %d fetches", got)
+	}
+}
+
+func TestTokenManagerLRU(t *testing.T) {
+	var count int32
+	m := NewTokenManager(TokenManagerOptions{MaxEntries: 2, Fetch: newCountingFetch(&count)})
+
+	ctx := context.TODO()
+	a := RequestOptions{ClientID: "a"}
+	b := RequestOptions{ClientID: "b"}
+	c := RequestOptions{ClientID: "c"}
+
+	m.Token(ctx, a)
+	m.Token(ctx, b)
+	m.Token(ctx, a) // a is most recently used
+	m.Token(ctx, c) // evicts b
+
+	if m.Len() != 2 {
+		t.Errorf("expected 2 entries, got %d", m.Len())
+	}
+
+	m.Token(ctx, a)
+	if got := atomic.LoadInt32(&count); got != 3 {
+		t.Errorf("expected a to stay cached, got %d fetches", got)
+	}
+
+	m.Token(ctx, b)
+	if got := atomic.LoadInt32(&count); got != 4 {
+		t.Errorf("expected b to be refetched, got %d fetches", got)
+	}
+}
+
+func TestTokenManagerIdleEviction(t *testing.T) {
+	var count int32
+	m := NewTokenManager(TokenManagerOptions{IdleTimeout: time.Minute, Fetch: newCountingFetch(&count)})
+
+	now := time.Now()
+	m.now = func() time.Time { return now }
+
+	ctx := context.TODO()
+	m.Token(ctx, RequestOptions{ClientID: "a"})
+
+	now = now.Add(30 * time.Second)
+	m.Token(ctx, RequestOptions{ClientID: "b"})
+
+	now = now.Add(45 * time.Second) // a idle for 75s, b for 45s
+	m.Token(ctx, RequestOptions{ClientID: "b"})
+
+	if m.Len() != 1 {
+		t.Errorf("expected idle entry evicted, got %d entries", m.Len())
+	}
+}
+
+func TestNormalizeScope(t *testing.T) {
+	for in, want := range map[string]string{
+		"":               "",
+		"b a":            "a b",
+		"  a  b a ":      "a b",
+		"write read":     "read write",
+		"read":           "read",
+		"c b a c b a ab": "a ab b c",
+	} {
+		if got := normalizeScope(in); got != want {
+			t.Errorf("normalizeScope(%q): expected %q, got %q", in, want, got)
+		}
+	}
+}
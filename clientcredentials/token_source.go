@@ -0,0 +1,235 @@
+package clientcredentials
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultExpiryMargin is the default safety margin subtracted from the
+// token lifetime reported by expires_in.
+const DefaultExpiryMargin = 30 * time.Second
+
+// FetchFunc is the signature of functions that fetch a token from the
+// token endpoint. SendRequest is the reference implementation.
+type FetchFunc func(ctx context.Context, options RequestOptions) (Response, error)
+
+// TokenSourceOptions contains options for creating a TokenSource.
+type TokenSourceOptions struct {
+	// RequestOptions is used to fetch tokens from the token endpoint.
+	RequestOptions RequestOptions
+
+	// ExpiryMargin is optional safety margin subtracted from expires_in.
+	// A cached token is considered expired when it is within ExpiryMargin
+	// of its actual expiration. If zero, DefaultExpiryMargin will be used.
+	// If the margin exceeds the token lifetime, half the lifetime is used.
+	ExpiryMargin time.Duration
+
+	// Fetch is optional function used to fetch tokens.
+	// If nil, SendRequest will be used.
+	Fetch FetchFunc
+
+	// Cache is optional persistent cache shared with other processes, like
+	// FileCache. Tokens without expires_in are not persisted. Cache
+	// failures are not fatal: when the cache cannot be locked the token is
+	// fetched without it, load failures are treated as a miss and store
+	// failures are ignored, since the fetched token is still valid.
+	Cache TokenCache
+}
+
+// TokenSource returns cached tokens, fetching a new one from the token
+// endpoint only when the cached token is near expiry.
+// TokenSource is safe for concurrent use by multiple goroutines.
+type TokenSource struct {
+	options TokenSourceOptions
+
+	mutex     sync.Mutex
+	token     Response
+	expiry    time.Time // zero means no known expiry
+	valid     bool
+	skipCache bool       // the persisted token was invalidated
+	fetching  *groupCall // in-flight fetch, if any
+
+	now func() time.Time
+}
+
+// NewTokenSource creates a TokenSource.
+func NewTokenSource(options TokenSourceOptions) *TokenSource {
+	if options.ExpiryMargin == 0 {
+		options.ExpiryMargin = DefaultExpiryMargin
+	}
+	if options.Fetch == nil {
+		options.Fetch = SendRequest
+	}
+	return &TokenSource{
+		options: options,
+		now:     time.Now,
+	}
+}
+
+// Token returns the cached token if it is still valid, otherwise it fetches
+// a new token from the token endpoint.
+// Tokens without expires_in are cached until Invalidate is called.
+//
+// Concurrent callers share a single fetch, which is not canceled when the
+// caller that started it gives up, like in RequestGroup. Every caller stops
+// waiting when its own context is done.
+func (ts *TokenSource) Token(ctx context.Context) (Response, error) {
+	ts.mutex.Lock()
+	if ts.validLocked() {
+		token := ts.token
+		ts.mutex.Unlock()
+		return token, nil
+	}
+	c := ts.fetching
+	if c == nil {
+		c = &groupCall{done: make(chan struct{})}
+		ts.fetching = c
+		go ts.fetch(ctx, c)
+	}
+	ts.mutex.Unlock()
+
+	select {
+	case <-c.done:
+		return c.resp, c.err
+	case <-ctx.Done():
+		return Response{}, ctx.Err()
+	}
+}
+
+// fetch obtains a new token for the callers waiting on c.
+func (ts *TokenSource) fetch(ctx context.Context, c *groupCall) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.resp, c.err = Response{}, fmt.Errorf("oauth2clientcredentials.TokenSource: fetch panic: %v", r)
+		}
+		ts.mutex.Lock()
+		ts.fetching = nil
+		ts.mutex.Unlock()
+		close(c.done)
+	}()
+
+	fetchCtx, cancel := detachContext(ctx)
+	defer cancel()
+
+	if ts.options.Cache != nil {
+		c.resp, c.err = ts.tokenFromCache(fetchCtx)
+		return
+	}
+
+	c.resp, c.err = ts.fetchUncached(fetchCtx)
+}
+
+// fetchUncached fetches a new token, keeping it in memory only.
+func (ts *TokenSource) fetchUncached(ctx context.Context) (Response, error) {
+	resp, err := ts.options.Fetch(ctx, ts.options.RequestOptions)
+	if err != nil {
+		return resp, err
+	}
+	ts.mutex.Lock()
+	ts.storeLocked(resp)
+	ts.mutex.Unlock()
+	return resp, nil
+}
+
+// tokenFromCache returns the persisted token if still valid, otherwise it
+// fetches and persists a new one, holding the cache lock so that
+// concurrent processes share a single fetch.
+func (ts *TokenSource) tokenFromCache(ctx context.Context) (Response, error) {
+	unlock, errLock := ts.options.Cache.Lock(ctx)
+	if errLock != nil {
+		// the cache is unusable, for example its directory is missing
+		return ts.fetchUncached(ctx)
+	}
+	defer unlock()
+
+	ts.mutex.Lock()
+	skipCache := ts.skipCache
+	ts.mutex.Unlock()
+
+	if !skipCache {
+		cached, found, errLoad := ts.options.Cache.Load()
+		if errLoad == nil && found && cached.Response.ExpiresIn > 0 {
+			lifetime := time.Duration(cached.Response.ExpiresIn) * time.Second
+			issued := cached.Expiry.Add(-lifetime)
+			expiry := expiryTime(issued, cached.Response.ExpiresIn, ts.options.ExpiryMargin)
+			if ts.now().Before(expiry) {
+				ts.mutex.Lock()
+				ts.token = cached.Response
+				ts.valid = true
+				ts.expiry = expiry
+				ts.mutex.Unlock()
+				return cached.Response, nil
+			}
+		}
+	}
+
+	now := ts.now()
+
+	resp, err := ts.options.Fetch(ctx, ts.options.RequestOptions)
+	if err != nil {
+		return resp, err
+	}
+
+	ts.mutex.Lock()
+	ts.storeLocked(resp)
+	ts.skipCache = false
+	ts.mutex.Unlock()
+
+	if resp.ExpiresIn > 0 {
+		lifetime := time.Duration(resp.ExpiresIn) * time.Second
+		ts.options.Cache.Store(CachedToken{Response: resp, Expiry: now.Add(lifetime)})
+	}
+
+	return resp, nil
+}
+
+// Invalidate discards the cached token, forcing the next call to Token to
+// fetch a new one.
+func (ts *TokenSource) Invalidate() {
+	ts.mutex.Lock()
+	ts.valid = false
+	ts.skipCache = true
+	ts.mutex.Unlock()
+}
+
+// invalidateToken discards the cached token only if it is still accessToken,
+// so that concurrent callers rejecting the same token trigger a single fetch.
+func (ts *TokenSource) invalidateToken(accessToken string) {
+	ts.mutex.Lock()
+	if ts.token.AccessToken == accessToken {
+		ts.valid = false
+		ts.skipCache = true
+	}
+	ts.mutex.Unlock()
+}
+
+func (ts *TokenSource) validLocked() bool {
+	if !ts.valid {
+		return false
+	}
+	if ts.expiry.IsZero() {
+		return true
+	}
+	return ts.now().Before(ts.expiry)
+}
+
+func (ts *TokenSource) storeLocked(resp Response) {
+	ts.token = resp
+	ts.valid = true
+	ts.expiry = expiryTime(ts.now(), resp.ExpiresIn, ts.options.ExpiryMargin)
+}
+
+// expiryTime returns the instant after which a token issued at now should
+// no longer be used. It returns the zero time if expiresIn is not positive.
+func expiryTime(now time.Time, expiresIn int, margin time.Duration) time.Time {
+	if expiresIn <= 0 {
+		return time.Time{}
+	}
+	lifetime := time.Duration(expiresIn) * time.Second
+	if margin >= lifetime {
+		margin = lifetime / 2
+	}
+	return now.Add(lifetime - margin)
+}
@@ -0,0 +1,136 @@
+package clientcredentials
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newCountingTokenServer(t *testing.T, expiresIn int, count *int32) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		n := atomic.AddInt32(count, 1)
+		accessToken := "token" + strconv.Itoa(int(n))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(EncodeResponseBody(accessToken, "", expiresIn)))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestTokenSourceCache(t *testing.T) {
+	var count int32
+	server := newCountingTokenServer(t, 100, &count)
+
+	ts := NewTokenSource(TokenSourceOptions{
+		RequestOptions: RequestOptions{TokenURL: server.URL},
+		ExpiryMargin:   10 * time.Second,
+	})
+
+	now := time.Now()
+	ts.now = func() time.Time { return now }
+
+	for range 3 {
+		tok, err := ts.Token(context.TODO())
+		if err != nil {
+			t.Fatalf("token: %v", err)
+		}
+		if tok.AccessToken != "token1" {
+			t.Errorf("expected token1, got %s", tok.AccessToken)
+		}
+	}
+
+	// still valid just before margin
+	now = now.Add(89 * time.Second)
+	if tok, _ := ts.Token(context.TODO()); tok.AccessToken != "token1" {
+		t.Errorf("expected token1 before margin, got %s", tok.AccessToken)
+	}
+
+	// within margin: must refresh
+	now = now.Add(2 * time.Second)
+	if tok, _ := ts.Token(context.TODO()); tok.AccessToken != "token2" {
+		t.Errorf("expected token2 after margin, got %s", tok.AccessToken)
+	}
+
+	ts.Invalidate()
+	if tok, _ := ts.Token(context.TODO()); tok.AccessToken != "token3" {
+		t.Errorf("expected token3 after invalidate, got %s", tok.AccessToken)
+	}
+
+	if got := atomic.LoadInt32(&count); got != 3 {
+		t.Errorf("expected 3 requests, got %d", got)
+	}
+}
+
+func TestTokenSourceConcurrent(t *testing.T) {
+	var count int32
+	server := newCountingTokenServer(t, 3600, &count)
+
+	ts := NewTokenSource(TokenSourceOptions{
+		RequestOptions: RequestOptions{TokenURL: server.URL},
+	})
+
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Go(func() {
+			if _, err := ts.Token(context.TODO()); err != nil {
+				t.Errorf("token: %v", err)
+			}
+		})
+	}
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&count); got != 1 {
+		t.Errorf("expected 1 request, got %d", got)
+	}
+}
+
+func TestTokenSourceCallerContext(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	started := make(chan struct{})
+	ts := NewTokenSource(TokenSourceOptions{
+		Fetch: func(_ context.Context, _ RequestOptions) (Response, error) {
+			close(started)
+			<-release // slow token endpoint
+			return Response{AccessToken: "at", ExpiresIn: 3600}, nil
+		},
+	})
+
+	go ts.Token(context.TODO())
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 100*time.Millisecond)
+	defer cancel()
+
+	begin := time.Now()
+	if _, err := ts.Token(ctx); err != context.DeadlineExceeded {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(begin); elapsed > time.Second {
+		t.Errorf("waiting caller ignored its deadline, took %v", elapsed)
+	}
+}
+
+func TestExpiryTime(t *testing.T) {
+	now := time.Unix(1000, 0)
+
+	if got := expiryTime(now, 0, time.Second); !got.IsZero() {
+		t.Errorf("expected zero expiry for missing expires_in, got %v", got)
+	}
+
+	if got, want := expiryTime(now, 60, 10*time.Second), now.Add(50*time.Second); !got.Equal(want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	// margin larger than lifetime falls back to half the lifetime
+	if got, want := expiryTime(now, 10, time.Minute), now.Add(5*time.Second); !got.Equal(want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,142 @@
+package clientcredentials
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Transport is an http.RoundTripper that injects tokens obtained from
+// Source into the Authorization header of outgoing requests.
+// When the resource server responds with 401, the cached token is
+// invalidated and the request is retried once with a fresh token.
+//
+// DPoP-bound tokens are sent with a DPoP proof created by the DPoP of the
+// Source RequestOptions. A 401 use_dpop_nonce challenge is retried once
+// with the supplied nonce and the same token.
+type Transport struct {
+	// Base is optional underlying RoundTripper.
+	// If nil, http.DefaultTransport will be used.
+	Base http.RoundTripper
+
+	// Source provides the tokens.
+	Source *TokenSource
+}
+
+// NewHTTPClient creates an *http.Client that authenticates every request
+// with tokens from source.
+func NewHTTPClient(source *TokenSource) *http.Client {
+	return &http.Client{Transport: &Transport{Source: source}}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	token, errToken := t.Source.Token(req.Context())
+	if errToken != nil {
+		closeRequestBody(req)
+		return nil, errToken
+	}
+
+	dpop := t.Source.options.RequestOptions.DPoP
+
+	first, errAuth := authorizedRequest(req, token, dpop)
+	if errAuth != nil {
+		closeRequestBody(req)
+		return nil, errAuth
+	}
+
+	resp, errDo := base.RoundTrip(first)
+	if errDo != nil {
+		return resp, errDo
+	}
+
+	nonceChallenge := false
+	if dpop != nil {
+		dpop.updateNonce(req.URL, resp.Header)
+		nonceChallenge = token.IsDPoP() && isDPoPNonceChallenge(resp)
+	}
+
+	if resp.StatusCode != http.StatusUnauthorized {
+		return resp, nil
+	}
+
+	// retry once, with the new nonce or with a fresh token
+
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return resp, nil // body cannot be rewound
+	}
+
+	if !nonceChallenge {
+		t.Source.invalidateToken(token.AccessToken)
+
+		token, errToken = t.Source.Token(req.Context())
+		if errToken != nil {
+			return resp, nil // keep the original 401
+		}
+	}
+
+	retry, errAuth := authorizedRequest(req, token, dpop)
+	if errAuth != nil {
+		return resp, nil
+	}
+	if req.GetBody != nil {
+		body, errBody := req.GetBody()
+		if errBody != nil {
+			return resp, nil
+		}
+		retry.Body = body
+	}
+
+	drainBody(resp.Body)
+
+	return base.RoundTrip(retry)
+}
+
+// authorizedRequest clones req adding the Authorization header and, for
+// DPoP-bound tokens, the DPoP proof.
+func authorizedRequest(req *http.Request, token Response, dpop *DPoP) (*http.Request, error) {
+	r := req.Clone(req.Context())
+	r.Header.Set("Authorization", authorizationType(token.TokenType)+" "+token.AccessToken)
+
+	if token.IsDPoP() {
+		if dpop == nil {
+			return nil, fmt.Errorf("oauth2clientcredentials: dpop-bound token requires RequestOptions.DPoP")
+		}
+		proof, err := dpop.Proof(req.Method, req.URL.String(), token.AccessToken)
+		if err != nil {
+			return nil, err
+		}
+		r.Header.Set("DPoP", proof)
+	}
+
+	return r, nil
+}
+
+// authorizationType returns the authorization scheme for a token type,
+// defaulting to Bearer.
+func authorizationType(tokenType string) string {
+	switch {
+	case tokenType == "" || strings.EqualFold(tokenType, "bearer"):
+		return "Bearer"
+	case strings.EqualFold(tokenType, TokenTypeDPoP):
+		return TokenTypeDPoP
+	}
+	return tokenType
+}
+
+func closeRequestBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
+
+func drainBody(body io.ReadCloser) {
+	io.Copy(io.Discard, io.LimitReader(body, 4096))
+	body.Close()
+}
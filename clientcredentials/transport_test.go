@@ -0,0 +1,104 @@
+package clientcredentials
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestTransport(t *testing.T) {
+	var tokenCount int32
+	tokenServer := newCountingTokenServer(t, 3600, &tokenCount)
+
+	var calls int32
+	resourceServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != "payload" {
+			t.Errorf("expected body payload, got '%s'", body)
+		}
+		// reject the first token to force a retry
+		if r.Header.Get("Authorization") != "Bearer token2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer resourceServer.Close()
+
+	client := NewHTTPClient(NewTokenSource(TokenSourceOptions{
+		RequestOptions: RequestOptions{TokenURL: tokenServer.URL},
+	}))
+
+	resp, err := client.Post(resourceServer.URL, "text/plain", bytes.NewBufferString("payload"))
+	if err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status 200, got %d", resp.StatusCode)
+	}
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Errorf("expected 2 resource calls, got %d", got)
+	}
+	if got := atomic.LoadInt32(&tokenCount); got != 2 {
+		t.Errorf("expected 2 token requests, got %d", got)
+	}
+
+	// cached token2 is reused
+	resp, err = client.Post(resourceServer.URL, "text/plain", bytes.NewBufferString("payload"))
+	if err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	resp.Body.Close()
+
+	if got := atomic.LoadInt32(&tokenCount); got != 2 {
+		t.Errorf("expected cached token, got %d token requests", got)
+	}
+}
+
+func TestTransportRetriesOnlyOnce(t *testing.T) {
+	var tokenCount int32
+	tokenServer := newCountingTokenServer(t, 3600, &tokenCount)
+
+	var calls int32
+	resourceServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer resourceServer.Close()
+
+	client := NewHTTPClient(NewTokenSource(TokenSourceOptions{
+		RequestOptions: RequestOptions{TokenURL: tokenServer.URL},
+	}))
+
+	resp, err := client.Get(resourceServer.URL)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected status 401, got %d", resp.StatusCode)
+	}
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Errorf("expected 2 resource calls, got %d", got)
+	}
+}
+
+func TestAuthorizationType(t *testing.T) {
+	for _, data := range []struct{ in, want string }{
+		{"", "Bearer"},
+		{"bearer", "Bearer"},
+		{"BEARER", "Bearer"},
+		{"MAC", "MAC"},
+	} {
+		if got := authorizationType(data.in); got != data.want {
+			t.Errorf("authorizationType(%q): expected %q, got %q", data.in, data.want, got)
+		}
+	}
+}
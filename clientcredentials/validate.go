@@ -0,0 +1,67 @@
+package clientcredentials
+
+import (
+	"errors"
+	"strings"
+)
+
+// ResponseDecoder is the signature of the DecodeResponseBody* functions.
+// data is a pooled buffer reused once the decoder returns, so decoders
+// must copy anything they keep from it, like strings, slices or
+// json.RawMessage values, instead of aliasing it.
+type ResponseDecoder func(data []byte) (Response, error)
+
+// ResponseFieldError reports a token response field rejected by
+// ValidateResponse. Use errors.As to retrieve it.
+type ResponseFieldError struct {
+	Field  string
+	Reason string
+}
+
+// Error implements the error interface.
+func (e *ResponseFieldError) Error() string {
+	return "oauth2clientcredentials: invalid token response field " + e.Field + ": " + e.Reason
+}
+
+// ValidateResponse checks a decoded token response against strict rules:
+//
+//   - access_token is required and must not be empty.
+//   - token_type is required and must be Bearer or DPoP (case-insensitive).
+//   - expires_in must not be negative.
+//
+// All violations are reported, joined, as *ResponseFieldError.
+func ValidateResponse(resp Response) error {
+	var errs []error
+
+	if resp.AccessToken == "" {
+		errs = append(errs, &ResponseFieldError{Field: "access_token", Reason: "missing or empty"})
+	}
+
+	switch {
+	case resp.TokenType == "":
+		errs = append(errs, &ResponseFieldError{Field: "token_type", Reason: "missing or empty"})
+	case !strings.EqualFold(resp.TokenType, "bearer") && !resp.IsDPoP():
+		errs = append(errs, &ResponseFieldError{Field: "token_type", Reason: "unsupported type: " + resp.TokenType})
+	}
+
+	if resp.ExpiresIn < 0 {
+		errs = append(errs, &ResponseFieldError{Field: "expires_in", Reason: "negative value"})
+	}
+
+	return errors.Join(errs...)
+}
+
+// Strict wraps decode so that decoded responses are checked with
+// ValidateResponse, applying the same rules to any DecodeResponseBody*
+// variant. For example:
+//
+//	options.DecodeResponse = clientcredentials.Strict(clientcredentials.DecodeResponseBody)
+func Strict(decode ResponseDecoder) ResponseDecoder {
+	return func(data []byte) (Response, error) {
+		resp, err := decode(data)
+		if err != nil {
+			return resp, err
+		}
+		return resp, ValidateResponse(resp)
+	}
+}
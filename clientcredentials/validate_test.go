@@ -0,0 +1,102 @@
+package clientcredentials
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var decoderTable = map[string]ResponseDecoder{
+	"DecodeResponseBody":                 DecodeResponseBody,
+	"DecodeResponseBodyFastJSON":         DecodeResponseBodyFastJSON,
+	"DecodeResponseBodyFastJSONSyncPool": DecodeResponseBodyFastJSONSyncPool,
+	"DecodeResponseBodySonnet":           DecodeResponseBodySonnet,
+	"DecodeResponseBodyCustomParser":     DecodeResponseBodyCustomParser,
+}
+
+type strictTest struct {
+	name        string
+	input       string
+	wantField   string // field expected in *ResponseFieldError
+	wantSuccess bool
+}
+
+var strictTests = []strictTest{
+	{"valid", `{"access_token":"at","token_type":"Bearer","expires_in":60}`, "", true},
+	{"lowercase bearer", `{"access_token":"at","token_type":"bearer"}`, "", true},
+	{"dpop", `{"access_token":"at","token_type":"DPoP"}`, "", true},
+	{"empty access_token", `{"access_token":"","token_type":"Bearer"}`, "access_token", false},
+	{"missing token_type", `{"access_token":"at"}`, "token_type", false},
+	{"unsupported token_type", `{"access_token":"at","token_type":"MAC"}`, "token_type", false},
+	{"negative expires_in", `{"access_token":"at","token_type":"Bearer","expires_in":-1}`, "expires_in", false},
+}
+
+func TestStrictDecoders(t *testing.T) {
+	for decoderName, decode := range decoderTable {
+		strict := Strict(decode)
+		for _, tt := range strictTests {
+			_, err := strict([]byte(tt.input))
+			if tt.wantSuccess {
+				if err != nil {
+					t.Errorf("%s: %s: unexpected error: %v", decoderName, tt.name, err)
+				}
+				continue
+			}
+			if err == nil {
+				t.Errorf("%s: %s: expected error", decoderName, tt.name)
+				continue
+			}
+			// the custom parser rejects an empty access_token by itself
+			var fieldErr *ResponseFieldError
+			if !errors.As(err, &fieldErr) {
+				if decoderName == "DecodeResponseBodyCustomParser" && tt.name == "empty access_token" {
+					continue
+				}
+				t.Errorf("%s: %s: expected *ResponseFieldError, got %v", decoderName, tt.name, err)
+				continue
+			}
+			if fieldErr.Field != tt.wantField {
+				t.Errorf("%s: %s: expected field %s, got %s", decoderName, tt.name, tt.wantField, fieldErr.Field)
+			}
+		}
+	}
+}
+
+func TestValidateResponseMultipleFields(t *testing.T) {
+	err := ValidateResponse(Response{ExpiresIn: -5})
+
+	var fields []string
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var fieldErr *ResponseFieldError
+		if errors.As(e, &fieldErr) {
+			fields = append(fields, fieldErr.Field)
+		}
+	}
+
+	if len(fields) != 3 {
+		t.Errorf("expected 3 field errors, got %v", fields)
+	}
+}
+
+func TestSendRequestStrict(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(`{"access_token":"at"}`))
+	}))
+	defer server.Close()
+
+	options := RequestOptions{TokenURL: server.URL}
+
+	if _, err := SendRequest(context.TODO(), options); err != nil {
+		t.Fatalf("lenient decoding: unexpected error: %v", err)
+	}
+
+	options.DecodeResponse = Strict(DecodeResponseBody)
+
+	_, err := SendRequest(context.TODO(), options)
+	var fieldErr *ResponseFieldError
+	if !errors.As(err, &fieldErr) || fieldErr.Field != "token_type" {
+		t.Errorf("expected token_type field error, got %v", err)
+	}
+}